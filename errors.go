@@ -15,4 +15,12 @@ var (
 	// ErrMetadataFetch indicates a failure while fetching metadata from the
 	// metadata server.
 	ErrMetadataFetch = errors.New("failed to fetch metadata from server")
+
+	// ErrShutdown indicates that one or more shutdown hooks failed or did not
+	// complete within their allotted time during a graceful shutdown.
+	ErrShutdown = errors.New("failed to shut down gracefully")
+
+	// ErrSidecarUnavailable indicates that a sidecar container did not start
+	// accepting connections on its port within the allotted time.
+	ErrSidecarUnavailable = errors.New("sidecar unavailable")
 )
@@ -4,7 +4,12 @@ go 1.24.1
 
 require (
 	cloud.google.com/go/compute/metadata v0.7.0
+	github.com/rs/zerolog v1.34.0
 	golang.org/x/sync v0.14.0
 )
 
-require golang.org/x/sys v0.33.0 // indirect
+require (
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.19 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+)
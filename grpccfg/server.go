@@ -0,0 +1,208 @@
+package grpccfg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joaopenteado/runcfg"
+	"github.com/joaopenteado/runcfg/zerologcfg/grpclog"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type serverConfig struct {
+	reflection      bool
+	tracerProvider  trace.TracerProvider
+	logger          *zerolog.Logger
+	shutdownTimeout time.Duration
+	signals         []os.Signal
+	serverOpts      []grpc.ServerOption
+}
+
+type ServerOption = option[serverConfig]
+
+// WithReflection registers the gRPC server reflection service, allowing tools
+// such as grpcurl to discover the services exposed by the server.
+func WithReflection() ServerOption {
+	return optionFunc[serverConfig](func(cfg *serverConfig) {
+		cfg.reflection = true
+	})
+}
+
+// WithTracerProvider instruments the server with OpenTelemetry tracing using
+// the given tracer provider, such as the one returned by
+// otelcfg.SetupTracerProvider. Health checks are not traced.
+func WithTracerProvider(tp trace.TracerProvider) ServerOption {
+	return optionFunc[serverConfig](func(cfg *serverConfig) {
+		cfg.tracerProvider = tp
+	})
+}
+
+// WithLogger adds grpclog.UnaryServerInterceptor and
+// grpclog.StreamServerInterceptor, which inject a ctx-bound logger in the
+// context of every RPC, the same way zerologcfg.Handler does for HTTP requests,
+// and log each RPC once it completes. Configure the logger with zerologcfg.Hook
+// to correlate log entries with traces.
+func WithLogger(logger zerolog.Logger) ServerOption {
+	return optionFunc[serverConfig](func(cfg *serverConfig) {
+		cfg.logger = &logger
+	})
+}
+
+// WithShutdownTimeout specifies how long to wait for in-flight RPCs to finish
+// once shutdown begins before closing all connections forcefully. By default,
+// runcfg.DefaultShutdownTimeout is used. Non-positive values are ignored.
+func WithShutdownTimeout(timeout time.Duration) ServerOption {
+	return optionFunc[serverConfig](func(cfg *serverConfig) {
+		if timeout > 0 {
+			cfg.shutdownTimeout = timeout
+		}
+	})
+}
+
+// WithShutdownSignals specifies the signals that trigger a graceful shutdown of
+// the server. By default, SIGTERM and SIGINT are used.
+func WithShutdownSignals(signals ...os.Signal) ServerOption {
+	return optionFunc[serverConfig](func(cfg *serverConfig) {
+		if len(signals) > 0 {
+			cfg.signals = signals
+		}
+	})
+}
+
+// WithServerOptions sets additional options for the gRPC server.
+func WithServerOptions(opts ...grpc.ServerOption) ServerOption {
+	return optionFunc[serverConfig](func(cfg *serverConfig) {
+		cfg.serverOpts = append(cfg.serverOpts, opts...)
+	})
+}
+
+// Server is a gRPC server bound to the port of a Cloud Run service. It embeds
+// the underlying *grpc.Server, so services can be registered on it directly.
+type Server struct {
+	*grpc.Server
+
+	// Health is the grpc.health.v1 service registered on the server. It
+	// reports SERVING while the server is running and NOT_SERVING once
+	// shutdown begins.
+	Health *health.Server
+
+	service *runcfg.Service
+	cfg     serverConfig
+}
+
+// NewServer creates a new gRPC server for a Cloud Run service. The server
+// registers the grpc.health.v1 service and, if enabled, the reflection
+// service. Register your own services on the returned server before calling
+// [Server.Serve].
+func NewServer(service *runcfg.Service, opts ...ServerOption) *Server {
+	cfg := serverConfig{
+		shutdownTimeout: runcfg.DefaultShutdownTimeout,
+		signals:         []os.Signal{syscall.SIGTERM, os.Interrupt},
+	}
+
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	var grpcOpts []grpc.ServerOption
+
+	if cfg.tracerProvider != nil {
+		grpcOpts = append(grpcOpts, grpc.StatsHandler(otelgrpc.NewServerHandler(
+			otelgrpc.WithTracerProvider(cfg.tracerProvider),
+			otelgrpc.WithFilter(filters.Not(filters.HealthCheck())),
+		)))
+	}
+
+	if cfg.logger != nil {
+		grpcOpts = append(grpcOpts,
+			grpc.ChainUnaryInterceptor(grpclog.UnaryServerInterceptor(*cfg.logger)),
+			grpc.ChainStreamInterceptor(grpclog.StreamServerInterceptor(*cfg.logger)),
+		)
+	}
+
+	grpcOpts = append(grpcOpts, cfg.serverOpts...)
+
+	srv := grpc.NewServer(grpcOpts...)
+
+	healthSrv := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthSrv)
+
+	if cfg.reflection {
+		reflection.Register(srv)
+	}
+
+	return &Server{
+		Server:  srv,
+		Health:  healthSrv,
+		service: service,
+		cfg:     cfg,
+	}
+}
+
+// Serve listens on the service port and serves gRPC over plaintext HTTP/2,
+// which is what Cloud Run forwards to containers when end-to-end HTTP/2 is
+// enabled. It blocks until ctx is canceled or one of the shutdown signals is
+// received, then marks the server as NOT_SERVING and gracefully stops it.
+// In-flight RPCs that do not finish within the shutdown timeout are canceled.
+//
+// Serve returns nil if the server was stopped gracefully, or an error if the
+// server failed to start or shutdown did not complete in time.
+func (s *Server) Serve(ctx context.Context) error {
+	ln, err := s.service.Listener()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, s.cfg.signals...)
+	defer stop()
+
+	s.Health.Resume()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Server.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		// The server stopped without being asked to
+		return err
+	case <-ctx.Done():
+	}
+
+	s.Health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(s.cfg.shutdownTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+	case <-timer.C:
+		s.Server.Stop()
+		<-stopped
+		err = errors.Join(runcfg.ErrShutdown, context.DeadlineExceeded)
+	}
+
+	if serveErr := <-errCh; serveErr != nil && !errors.Is(serveErr, grpc.ErrServerStopped) {
+		err = errors.Join(err, serveErr)
+	}
+
+	return err
+}
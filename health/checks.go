@@ -0,0 +1,71 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/joaopenteado/runcfg"
+)
+
+// ErrTelemetryNotInitialized indicates that a telemetry provider passed to
+// TelemetryCheck was not initialized.
+var ErrTelemetryNotInitialized = errors.New("telemetry provider not initialized")
+
+// MetadataCheck returns a check that passes if the metadata server is
+// reachable, by fetching the given fields with [runcfg.Metadata.Reload]. The
+// region is always fetched as well, since the metadata client caches the
+// project ID, project number and instance ID after the first success, and
+// fetching only those would never contact the server again.
+func MetadataCheck(fields runcfg.MetadataField) CheckFunc {
+	fields |= runcfg.MetadataRegion
+
+	return func(ctx context.Context) error {
+		var m runcfg.Metadata
+		return m.Reload(ctx, fields)
+	}
+}
+
+// Flusher is implemented by telemetry providers that can flush buffered
+// telemetry to their exporters, such as the tracer and meter providers
+// returned by otelcfg.SetupTracerProvider and otelcfg.SetupMeterProvider.
+type Flusher interface {
+	ForceFlush(ctx context.Context) error
+}
+
+// TelemetryCheck returns a check that passes if every provider is initialized
+// and able to flush to its exporter. Since flushing exports any buffered
+// telemetry, register this check for the startup probe only, using
+// WithProbes(ProbeStartup).
+func TelemetryCheck(providers ...Flusher) CheckFunc {
+	return func(ctx context.Context) error {
+		var errs []error
+		for i, p := range providers {
+			if isNil(p) {
+				errs = append(errs, fmt.Errorf("provider %d: %w", i, ErrTelemetryNotInitialized))
+				continue
+			}
+			if err := p.ForceFlush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// isNil reports whether p is nil, including typed nil pointers, such as a nil
+// *sdktrace.TracerProvider, which are not equal to a nil interface.
+func isNil(p Flusher) bool {
+	if p == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(p)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
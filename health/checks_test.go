@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type flusher struct {
+	err error
+}
+
+func (f *flusher) ForceFlush(ctx context.Context) error {
+	return f.err
+}
+
+func TestTelemetryCheck(t *testing.T) {
+	errFlush := errors.New("flush failed")
+
+	tests := []struct {
+		name      string
+		providers []Flusher
+		wantErr   error
+	}{
+		{
+			name:      "no providers",
+			providers: nil,
+		},
+		{
+			name:      "initialized",
+			providers: []Flusher{&flusher{}, &flusher{}},
+		},
+		{
+			name:      "nil interface",
+			providers: []Flusher{&flusher{}, nil},
+			wantErr:   ErrTelemetryNotInitialized,
+		},
+		{
+			name:      "typed nil",
+			providers: []Flusher{(*flusher)(nil)},
+			wantErr:   ErrTelemetryNotInitialized,
+		},
+		{
+			name:      "flush error",
+			providers: []Flusher{&flusher{err: errFlush}},
+			wantErr:   errFlush,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := TelemetryCheck(tt.providers...)(context.Background())
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("TelemetryCheck() = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("TelemetryCheck() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Probe represents a kind of Cloud Run container probe a check applies to.
+type Probe uint
+
+const (
+	// ProbeNone represents no probes.
+	ProbeNone Probe = 0
+
+	// ProbeStartup represents the startup probe.
+	ProbeStartup Probe = 1 << iota
+
+	// ProbeLiveness represents the liveness probe.
+	ProbeLiveness
+
+	// ProbeReadiness represents the readiness probe.
+	ProbeReadiness
+
+	// ProbeAll represents all probes.
+	ProbeAll = ^Probe(0)
+)
+
+// Default paths used by [Registry.Mount].
+const (
+	StartupPath   = "/startupz"
+	LivenessPath  = "/livez"
+	ReadinessPath = "/readyz"
+)
+
+// DefaultTimeout is the default amount of time a check is given to complete.
+const DefaultTimeout = time.Second
+
+// Status is the outcome of a check, a warmup function or a whole probe.
+type Status string
+
+const (
+	// StatusOK indicates the check passed.
+	StatusOK Status = "ok"
+
+	// StatusFail indicates the check failed or timed out.
+	StatusFail Status = "fail"
+
+	// StatusPending indicates a warmup function has not finished yet.
+	StatusPending Status = "pending"
+)
+
+// ErrWarmupPending indicates that a warmup function has not finished yet.
+var ErrWarmupPending = errors.New("warmup has not finished")
+
+// CheckFunc reports whether a component is healthy by returning nil. The
+// context passed to the function carries the check timeout.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	fn      CheckFunc
+	timeout time.Duration
+	probes  Probe
+}
+
+type CheckOption func(*check)
+
+// WithTimeout specifies the amount of time the check is given to complete
+// before being considered failed. By default, DefaultTimeout is used.
+// Non-positive values are ignored.
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		if timeout > 0 {
+			c.timeout = timeout
+		}
+	}
+}
+
+// WithProbes specifies which probes the check applies to. By default, checks
+// apply to the startup and readiness probes only, since a failing liveness
+// probe causes Cloud Run to restart the container. Only register checks in the
+// liveness probe if restarting the container would fix the failure.
+func WithProbes(probes Probe) CheckOption {
+	return func(c *check) {
+		c.probes = probes
+	}
+}
+
+// Result contains the outcome of a single check or warmup function.
+type Result struct {
+	Status   Status `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Report contains the outcome of a probe. Status is StatusOK only if every
+// check in the probe passed.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type warmup struct {
+	done bool
+	err  error
+}
+
+// Registry holds named health checks and warmup functions, and serves them as
+// HTTP probe handlers. The zero value is ready to use.
+type Registry struct {
+	mu      sync.RWMutex
+	checks  []check
+	warmups map[string]*warmup
+}
+
+// NewRegistry creates a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a named check to the registry. Use options to specify its
+// timeout and which probes it applies to. Registering a check with a name
+// already in use replaces the previous check.
+func (r *Registry) Register(name string, fn CheckFunc, opts ...CheckOption) {
+	c := check{
+		name:    name,
+		fn:      fn,
+		timeout: DefaultTimeout,
+		probes:  ProbeStartup | ProbeReadiness,
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.checks {
+		if r.checks[i].name == name {
+			r.checks[i] = c
+			return
+		}
+	}
+	r.checks = append(r.checks, c)
+}
+
+// Warmup runs fn in a new goroutine. The startup probe keeps failing until
+// every warmup function has returned nil. If a warmup function returns an
+// error or panics, the startup probe fails permanently so Cloud Run eventually
+// replaces the instance.
+func (r *Registry) Warmup(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	w := &warmup{}
+
+	r.mu.Lock()
+	if r.warmups == nil {
+		r.warmups = make(map[string]*warmup)
+	}
+	r.warmups[name] = w
+	r.mu.Unlock()
+
+	go func() {
+		err := call(ctx, fn)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		w.done = true
+		w.err = err
+	}()
+}
+
+// Check runs all checks that apply to the given probe concurrently and
+// returns a report with their results. For the startup probe, the state of
+// every warmup function is included as well.
+func (r *Registry) Check(ctx context.Context, probe Probe) Report {
+	r.mu.RLock()
+	var checks []check
+	for _, c := range r.checks {
+		if c.probes&probe != 0 {
+			checks = append(checks, c)
+		}
+	}
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]Result, len(checks)),
+	}
+
+	if probe&ProbeStartup != 0 {
+		for name, w := range r.warmups {
+			res := Result{Status: StatusOK}
+			switch {
+			case !w.done:
+				res = Result{Status: StatusPending, Error: ErrWarmupPending.Error()}
+			case w.err != nil:
+				res = Result{Status: StatusFail, Error: w.err.Error()}
+			}
+			if res.Status != StatusOK {
+				report.Status = StatusFail
+			}
+			report.Checks["warmup:"+name] = res
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+		report.Checks[c.name] = results[i]
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, c check) (res Result) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		res.Duration = time.Since(start).String()
+	}()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- call(ctx, c.fn)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		// Do not wait for checks that ignore their context
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		return Result{Status: StatusFail, Error: err.Error()}
+	}
+	return Result{Status: StatusOK}
+}
+
+// call calls fn, returning a panic in fn as an error. It must be called in the
+// goroutine running fn, as a panic cannot be recovered from any other
+// goroutine.
+func call(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// Handler returns an HTTP handler that runs the checks for the given probe and
+// responds with a JSON report. The response status is 200 if all checks pass
+// and 503 otherwise.
+func (r *Registry) Handler(probe Probe) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Check(req.Context(), probe)
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// StartupHandler returns the handler for the startup probe.
+func (r *Registry) StartupHandler() http.Handler {
+	return r.Handler(ProbeStartup)
+}
+
+// LivenessHandler returns the handler for the liveness probe.
+func (r *Registry) LivenessHandler() http.Handler {
+	return r.Handler(ProbeLiveness)
+}
+
+// ReadinessHandler returns the handler for the readiness probe.
+func (r *Registry) ReadinessHandler() http.Handler {
+	return r.Handler(ProbeReadiness)
+}
+
+// Mount registers the startup, liveness and readiness handlers on mux under
+// StartupPath, LivenessPath and ReadinessPath respectively.
+func (r *Registry) Mount(mux *http.ServeMux) {
+	mux.Handle("GET "+StartupPath, r.StartupHandler())
+	mux.Handle("GET "+LivenessPath, r.LivenessHandler())
+	mux.Handle("GET "+ReadinessPath, r.ReadinessHandler())
+}
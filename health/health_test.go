@@ -0,0 +1,188 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckRecoversPanic(t *testing.T) {
+	r := NewRegistry()
+	r.Register("panics", func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	report := r.Check(context.Background(), ProbeReadiness)
+	if report.Status != StatusFail {
+		t.Errorf("Status = %q, want %q", report.Status, StatusFail)
+	}
+
+	res := report.Checks["panics"]
+	if res.Status != StatusFail || !strings.Contains(res.Error, "panic: boom") {
+		t.Errorf("Checks[panics] = %+v, want a failure reporting the panic", res)
+	}
+}
+
+// waitWarmup waits until the warmup function with the given name finishes.
+func waitWarmup(t *testing.T, r *Registry, name string) Result {
+	t.Helper()
+
+	for range 100 {
+		res := r.Check(context.Background(), ProbeStartup).Checks["warmup:"+name]
+		if res.Status != StatusPending {
+			return res
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("warmup %s did not finish", name)
+	return Result{}
+}
+
+func TestWarmup(t *testing.T) {
+	tests := []struct {
+		name      string
+		fn        func(ctx context.Context) error
+		want      Status
+		wantError string
+	}{
+		{
+			name: "ok",
+			fn:   func(ctx context.Context) error { return nil },
+			want: StatusOK,
+		},
+		{
+			name:      "error",
+			fn:        func(ctx context.Context) error { return errors.New("cache unavailable") },
+			want:      StatusFail,
+			wantError: "cache unavailable",
+		},
+		{
+			name:      "panic",
+			fn:        func(ctx context.Context) error { panic("boom") },
+			want:      StatusFail,
+			wantError: "panic: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			release := make(chan struct{})
+			r.Warmup(context.Background(), "cache", func(ctx context.Context) error {
+				<-release
+				return tt.fn(ctx)
+			})
+
+			report := r.Check(context.Background(), ProbeStartup)
+			if res := report.Checks["warmup:cache"]; report.Status != StatusFail || res.Status != StatusPending {
+				t.Errorf("Check() = %+v before the warmup finished, want it pending", report)
+			}
+			if report := r.Check(context.Background(), ProbeReadiness); report.Status != StatusOK {
+				t.Errorf("readiness Check() = %+v, want warmups to only apply to the startup probe", report)
+			}
+
+			close(release)
+			res := waitWarmup(t, r, "cache")
+			if res.Status != tt.want || !strings.Contains(res.Error, tt.wantError) {
+				t.Errorf("Checks[warmup:cache] = %+v, want status %q and error %q", res, tt.want, tt.wantError)
+			}
+			if report := r.Check(context.Background(), ProbeStartup); report.Status != tt.want {
+				t.Errorf("Status = %q, want %q", report.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		want       Status
+	}{
+		{
+			name:       "ok",
+			wantStatus: http.StatusOK,
+			want:       StatusOK,
+		},
+		{
+			name:       "fail",
+			err:        errors.New("database unavailable"),
+			wantStatus: http.StatusServiceUnavailable,
+			want:       StatusFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			r.Register("database", func(ctx context.Context) error { return tt.err })
+
+			rec := httptest.NewRecorder()
+			r.Handler(ProbeReadiness).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReadinessPath, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+
+			var report Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("invalid report: %v", err)
+			}
+			if report.Status != tt.want || report.Checks["database"].Status != tt.want {
+				t.Errorf("report = %+v, want status %q", report, tt.want)
+			}
+		})
+	}
+}
+
+func TestMount(t *testing.T) {
+	r := NewRegistry()
+	r.Register("startup", func(ctx context.Context) error { return nil }, WithProbes(ProbeStartup))
+	r.Register("liveness", func(ctx context.Context) error { return nil }, WithProbes(ProbeLiveness))
+	r.Register("readiness", func(ctx context.Context) error { return nil }, WithProbes(ProbeReadiness))
+
+	mux := http.NewServeMux()
+	r.Mount(mux)
+
+	tests := []struct {
+		path  string
+		check string
+	}{
+		{StartupPath, "startup"},
+		{LivenessPath, "liveness"},
+		{ReadinessPath, "readiness"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+
+			var report Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("invalid report: %v", err)
+			}
+			if _, ok := report.Checks[tt.check]; !ok || len(report.Checks) != 1 {
+				t.Errorf("checks = %v, want only %s", report.Checks, tt.check)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ReadinessPath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST %s status = %d, want %d", ReadinessPath, rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"os"
 	"strconv"
+
+	"github.com/rs/zerolog"
 )
 
 // Job contains environment variables available to Cloud Run jobs.
@@ -185,3 +187,14 @@ func (j *Job) EnvDecode(ctx context.Context, val string) error {
 
 	return j.Reload()
 }
+
+// MarshalZerologObject implements [zerolog.LogObjectMarshaler], so that the
+// configuration can be logged with zerolog.Event.Object, such as at startup
+// for debugging.
+func (j *Job) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("name", j.Name).
+		Str("execution", j.Execution).
+		Uint("taskIndex", j.TaskIndex).
+		Uint("taskAttempt", j.TaskAttempt).
+		Uint("taskCount", j.TaskCount)
+}
@@ -0,0 +1,239 @@
+package runcfg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultShutdownTimeout is the default total time budget for running all
+// shutdown hooks. Cloud Run sends SIGTERM and waits 10 seconds before sending
+// SIGKILL, so the default leaves a small margin for the process to exit.
+const DefaultShutdownTimeout = 9 * time.Second
+
+// ShutdownHook is a function called during graceful shutdown. The context
+// passed to the hook carries the deadline allotted to it.
+type ShutdownHook func(ctx context.Context) error
+
+// Shutdowner is implemented by values that can be shut down gracefully, such
+// as *http.Server and the tracer and meter providers returned by
+// otelcfg.SetupTracerProvider and otelcfg.SetupMeterProvider.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+type shutdownHook struct {
+	name string
+	fn   ShutdownHook
+}
+
+// Lifecycle orchestrates the graceful shutdown of a Cloud Run container.
+// Hooks registered with [Lifecycle.OnShutdown] are run in registration order
+// once a shutdown signal is received, sharing a total deadline budget.
+type Lifecycle struct {
+	timeout time.Duration
+	signals []os.Signal
+	logger  *zerolog.Logger
+
+	mu    sync.Mutex
+	hooks []shutdownHook
+
+	once sync.Once
+	err  error
+}
+
+type LifecycleOption func(*Lifecycle)
+
+// WithShutdownTimeout specifies the total time budget for running all shutdown
+// hooks. By default, DefaultShutdownTimeout is used. Non-positive values are
+// ignored.
+func WithShutdownTimeout(timeout time.Duration) LifecycleOption {
+	return func(l *Lifecycle) {
+		if timeout > 0 {
+			l.timeout = timeout
+		}
+	}
+}
+
+// WithShutdownSignals specifies the signals that trigger a graceful shutdown
+// when calling [Lifecycle.Wait]. By default, SIGTERM and SIGINT are used.
+func WithShutdownSignals(signals ...os.Signal) LifecycleOption {
+	return func(l *Lifecycle) {
+		if len(signals) > 0 {
+			l.signals = signals
+		}
+	}
+}
+
+// WithLifecycleLogger specifies the logger used to report each shutdown step.
+// By default, the logger stored in the context passed to [Lifecycle.Wait] or
+// [Lifecycle.Shutdown] is used, as returned by zerolog.Ctx.
+func WithLifecycleLogger(logger zerolog.Logger) LifecycleOption {
+	return func(l *Lifecycle) {
+		l.logger = &logger
+	}
+}
+
+// NewLifecycle creates a new Lifecycle. Use options to customize the shutdown
+// timeout, the signals being listened to and the logger.
+func NewLifecycle(opts ...LifecycleOption) *Lifecycle {
+	l := &Lifecycle{
+		timeout: DefaultShutdownTimeout,
+		signals: []os.Signal{syscall.SIGTERM, os.Interrupt},
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// OnShutdown registers a named hook to be run during shutdown. Hooks are run
+// sequentially in the order they were registered, so register the hooks that
+// stop accepting work (e.g. HTTP servers) before the ones that flush
+// telemetry.
+func (l *Lifecycle) OnShutdown(name string, hook ShutdownHook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hooks = append(l.hooks, shutdownHook{name: name, fn: hook})
+}
+
+// Wait blocks until one of the shutdown signals is received or ctx is done,
+// then runs all shutdown hooks by calling [Lifecycle.Shutdown]. The hooks
+// receive a context that is not canceled together with ctx but retains its
+// values.
+func (l *Lifecycle) Wait(ctx context.Context) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, l.signals...)
+	defer signal.Stop(sigCh)
+
+	logger := l.loggerFrom(ctx)
+
+	select {
+	case sig := <-sigCh:
+		logger.Info().Str("signal", sig.String()).Msg("received shutdown signal")
+	case <-ctx.Done():
+		logger.Info().Err(context.Cause(ctx)).Msg("context done, shutting down")
+	}
+
+	return l.Shutdown(context.WithoutCancel(ctx))
+}
+
+// Shutdown runs all registered hooks in order. The total timeout budget is
+// split across the hooks: each hook is given an equal share of the time that
+// is left, so time not used by a hook is passed on to the following ones. If
+// ctx has an earlier deadline than the budget, it takes precedence.
+//
+// Every hook is run even if a previous one fails. Shutdown returns an error
+// derived from ErrShutdown joining all hook errors, if any. Subsequent calls
+// return the result of the first call without running the hooks again.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.once.Do(func() {
+		l.err = l.shutdown(ctx)
+	})
+	return l.err
+}
+
+func (l *Lifecycle) shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	hooks := make([]shutdownHook, len(l.hooks))
+	copy(hooks, l.hooks)
+	l.mu.Unlock()
+
+	logger := l.loggerFrom(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	start := time.Now()
+	logger.Info().
+		Int("hooks", len(hooks)).
+		Dur("timeout", time.Until(deadline)).
+		Msg("starting graceful shutdown")
+
+	var errs []error
+	for i, hook := range hooks {
+		budget := time.Until(deadline) / time.Duration(len(hooks)-i)
+
+		hookStart := time.Now()
+		err := runShutdownHook(ctx, hook.fn, budget)
+		elapsed := time.Since(hookStart)
+
+		if err != nil {
+			logger.Error().
+				Err(err).
+				Str("hook", hook.name).
+				Dur("budget", budget).
+				Dur("elapsed", elapsed).
+				Msg("shutdown hook failed")
+			errs = append(errs, fmt.Errorf("%s: %w", hook.name, err))
+			continue
+		}
+
+		logger.Info().
+			Str("hook", hook.name).
+			Dur("budget", budget).
+			Dur("elapsed", elapsed).
+			Msg("shutdown hook completed")
+	}
+
+	if len(errs) > 0 {
+		logger.Error().Dur("elapsed", time.Since(start)).Msg("graceful shutdown completed with errors")
+		return errors.Join(append([]error{ErrShutdown}, errs...)...)
+	}
+
+	logger.Info().Dur("elapsed", time.Since(start)).Msg("graceful shutdown completed")
+	return nil
+}
+
+func runShutdownHook(ctx context.Context, hook ShutdownHook, budget time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return hook(ctx)
+}
+
+func (l *Lifecycle) loggerFrom(ctx context.Context) *zerolog.Logger {
+	if l.logger != nil {
+		return l.logger
+	}
+	return zerolog.Ctx(ctx)
+}
+
+// ShutdownHTTPServer returns a shutdown hook that gracefully shuts down srv,
+// waiting for active connections to become idle. If the hook deadline expires
+// before that happens, the remaining connections are closed forcefully.
+func ShutdownHTTPServer(srv *http.Server) ShutdownHook {
+	return func(ctx context.Context) error {
+		err := srv.Shutdown(ctx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errors.Join(err, srv.Close())
+		}
+		return err
+	}
+}
+
+// ShutdownProvider returns a shutdown hook for any value implementing
+// [Shutdowner], such as the tracer and meter providers returned by
+// otelcfg.SetupTracerProvider and otelcfg.SetupMeterProvider. Shutting down a
+// provider flushes any telemetry that is still buffered.
+func ShutdownProvider(p Shutdowner) ShutdownHook {
+	return p.Shutdown
+}
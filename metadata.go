@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"cloud.google.com/go/compute/metadata"
+	"github.com/rs/zerolog"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -32,6 +33,9 @@ const (
 	// MetadataServiceAccountEmail represents the service account email.
 	MetadataServiceAccountEmail
 
+	// MetadataZone represents the zone.
+	MetadataZone
+
 	// MetadataAll represents all metadata fields.
 	MetadataAll = ^MetadataField(0)
 )
@@ -71,6 +75,13 @@ var (
 	// values returned by the metadata server will override these when
 	// MetadataServiceAccountEmail is included in the fields to fetch.
 	EnvServiceAccountEmail = []string{"GOOGLE_SERVICE_ACCOUNT_EMAIL"}
+
+	// EnvZone is a list of environment variable names that are used by
+	// default to load the zone. Variables are checked in order, with the first
+	// non-empty value taking precedence. The values returned by the metadata
+	// server will override these when MetadataZone is included in the fields
+	// to fetch.
+	EnvZone = []string{"CLOUDSDK_COMPUTE_ZONE", "GOOGLE_CLOUD_ZONE", "GCP_ZONE"}
 )
 
 // Metadata contains information from the instance metadata server.
@@ -94,6 +105,9 @@ type Metadata struct {
 
 	// ServiceAccountEmail for the service identity of this Cloud Run service.
 	ServiceAccountEmail string
+
+	// Zone the instance is running in.
+	Zone string
 }
 
 func defaultMetadata() *Metadata {
@@ -103,6 +117,7 @@ func defaultMetadata() *Metadata {
 		Region:              GetFirstEnv(EnvRegion...),
 		InstanceID:          GetFirstEnv(EnvInstanceID...),
 		ServiceAccountEmail: GetFirstEnv(EnvServiceAccountEmail...),
+		Zone:                GetFirstEnv(EnvZone...),
 	}
 }
 
@@ -178,6 +193,20 @@ func WithDefaultServiceAccountEmail(serviceAccountEmails ...string) MetadataLoad
 	}
 }
 
+// WithDefaultZone specifies the default zone to use if the environment variable
+// is not set. If multiple zones are provided, the first non-empty zone will be
+// used.
+func WithDefaultZone(zones ...string) MetadataLoadOption {
+	return func(o *Metadata) {
+		for _, zone := range zones {
+			if zone != "" {
+				o.Zone = zone
+				break
+			}
+		}
+	}
+}
+
 // LoadMetadata loads the metadata from the Cloud Run metadata server. It
 // returns a pointer to a new Metadata struct with the loaded values. Data is
 // only loaded from the metadata server if the metadataFields parameter is set
@@ -190,8 +219,8 @@ func WithDefaultServiceAccountEmail(serviceAccountEmails ...string) MetadataLoad
 //
 // By default, values not loaded from the metadata server will be loaded from
 // the first non-empty value of the environment variables listed in
-// EnvProjectID, EnvProjectNumber, EnvRegion, EnvInstanceID, and
-// EnvServiceAccountEmail.
+// EnvProjectID, EnvProjectNumber, EnvRegion, EnvInstanceID,
+// EnvServiceAccountEmail, and EnvZone.
 func LoadMetadata(ctx context.Context, metadataFields MetadataField, opts ...MetadataLoadOption) (*Metadata, error) {
 	// Default values
 	m := &Metadata{}
@@ -217,6 +246,9 @@ func LoadMetadata(ctx context.Context, metadataFields MetadataField, opts ...Met
 	if serviceAccountEmail := GetFirstEnv(EnvServiceAccountEmail...); serviceAccountEmail != "" {
 		m.ServiceAccountEmail = serviceAccountEmail
 	}
+	if zone := GetFirstEnv(EnvZone...); zone != "" {
+		m.Zone = zone
+	}
 
 	// No need to fetch data that is already set by default options or envs
 	if m.ProjectID != "" {
@@ -234,6 +266,9 @@ func LoadMetadata(ctx context.Context, metadataFields MetadataField, opts ...Met
 	if m.ServiceAccountEmail != "" {
 		metadataFields &= ^MetadataServiceAccountEmail
 	}
+	if m.Zone != "" {
+		metadataFields &= ^MetadataZone
+	}
 
 	// Reload metadata from the server
 	if err := m.Reload(ctx, metadataFields); err != nil {
@@ -317,6 +352,18 @@ func (m *Metadata) Reload(ctx context.Context, metadataFields MetadataField) err
 		})
 	}
 
+	if metadataFields&MetadataZone != 0 {
+		g.Go(func() error {
+			res, err := metadata.GetWithContext(ctx, "instance/zone")
+			if err != nil {
+				return fmt.Errorf("failed to fetch zone: %w", err)
+			}
+			// Zone is returned in the format projects/{num}/zones/{name}
+			m.Zone = res[strings.LastIndexByte(res, '/')+1:]
+			return nil
+		})
+	}
+
 	if err := g.Wait(); err != nil {
 		return errors.Join(ErrMetadataFetch, err)
 	}
@@ -381,5 +428,38 @@ func (m *Metadata) EnvDecode(ctx context.Context, val string) error {
 		}
 	}
 
+	if m.Zone == "" {
+		if defaults.Zone != "" {
+			m.Zone = defaults.Zone
+		} else {
+			metadataFields |= MetadataZone
+		}
+	}
+
 	return m.Reload(ctx, metadataFields)
 }
+
+// MarshalZerologObject implements [zerolog.LogObjectMarshaler], so that the
+// metadata can be logged with zerolog.Event.Object, such as at startup for
+// debugging. The name of the service account is masked, so only the domain of
+// its email, such as developer.gserviceaccount.com, is logged.
+func (m *Metadata) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("projectId", m.ProjectID).
+		Str("projectNumber", m.ProjectNumber).
+		Str("region", m.Region).
+		Str("instanceId", m.InstanceID).
+		Str("serviceAccountEmail", maskEmail(m.ServiceAccountEmail)).
+		Str("zone", m.Zone)
+}
+
+// maskEmail replaces the local part of email with asterisks, keeping the
+// domain. Strings that are not email addresses are masked entirely.
+func maskEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+	if i := strings.LastIndexByte(email, '@'); i > 0 {
+		return "***" + email[i:]
+	}
+	return "***"
+}
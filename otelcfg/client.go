@@ -0,0 +1,171 @@
+package otelcfg
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/oauth2"
+)
+
+type clientConfig struct {
+	base          http.RoundTripper
+	audience      string
+	timeout       time.Duration
+	transportOpts []otelhttp.Option
+}
+
+type ClientOption = option[clientConfig]
+
+// WithBaseTransport sets the transport used to make requests. Defaults to
+// http.DefaultTransport.
+func WithBaseTransport(base http.RoundTripper) ClientOption {
+	return optionFunc[clientConfig](func(cfg *clientConfig) {
+		cfg.base = base
+	})
+}
+
+// WithIDTokenAudience authenticates requests with ID tokens of the default
+// service account for the given audience, fetched from the metadata server.
+// To call a Cloud Run service that requires authentication, use its URL as
+// the audience.
+func WithIDTokenAudience(audience string) ClientOption {
+	return optionFunc[clientConfig](func(cfg *clientConfig) {
+		cfg.audience = audience
+	})
+}
+
+// WithClientTimeout sets the time limit for requests made by the client,
+// including reading the response body. No limit is set by default.
+func WithClientTimeout(timeout time.Duration) ClientOption {
+	return optionFunc[clientConfig](func(cfg *clientConfig) {
+		cfg.timeout = timeout
+	})
+}
+
+// WithTransportOptions sets additional options for otelhttp.NewTransport.
+func WithTransportOptions(opts ...otelhttp.Option) ClientOption {
+	return optionFunc[clientConfig](func(cfg *clientConfig) {
+		cfg.transportOpts = append(cfg.transportOpts, opts...)
+	})
+}
+
+// NewHTTPClient returns an HTTP client for calling other services. Its
+// transport records client spans and metrics with otelhttp, propagates the
+// trace context of requests using the global text map propagator, as set by
+// SetupTracerProvider, and logs failed requests with the logger of the request
+// context, as returned by zerolog.Ctx.
+//
+// Pass the request context with http.NewRequestWithContext so that client
+// spans are children of the current span and failures are logged with its
+// logger.
+func NewHTTPClient(opts ...ClientOption) *http.Client {
+	cfg := clientConfig{
+		base: http.DefaultTransport,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	rt := cfg.base
+	if cfg.audience != "" {
+		rt = &oauth2.Transport{
+			Source: oauth2.ReuseTokenSource(nil, idTokenSource{audience: cfg.audience}),
+			Base:   rt,
+		}
+	}
+	rt = loggingTransport{base: rt}
+
+	return &http.Client{
+		Transport: otelhttp.NewTransport(rt, cfg.transportOpts...),
+		Timeout:   cfg.timeout,
+	}
+}
+
+// loggingTransport is a transport that logs failed requests.
+type loggingTransport struct {
+	base http.RoundTripper
+}
+
+func (t loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.base.RoundTrip(req)
+
+	logger := zerolog.Ctx(req.Context())
+	switch {
+	case err != nil:
+		logger.Error().
+			Err(err).
+			Str("method", req.Method).
+			Str("url", req.URL.Redacted()).
+			Dur("latency", time.Since(start)).
+			Msg("request failed")
+	case res.StatusCode >= http.StatusInternalServerError:
+		logger.Warn().
+			Str("method", req.Method).
+			Str("url", req.URL.Redacted()).
+			Int("status", res.StatusCode).
+			Dur("latency", time.Since(start)).
+			Msg("request failed")
+	}
+
+	return res, err
+}
+
+// idTokenSource is a token source of ID tokens for an audience, fetched from
+// the metadata server.
+type idTokenSource struct {
+	audience string
+}
+
+func (s idTokenSource) Token() (*oauth2.Token, error) {
+	idToken, err := metadata.GetWithContext(context.Background(),
+		"instance/service-accounts/default/identity?audience="+url.QueryEscape(s.audience)+"&format=full")
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Token{
+		AccessToken: idToken,
+		TokenType:   "Bearer",
+		Expiry:      idTokenExpiry(idToken),
+	}, nil
+}
+
+// idTokenFallbackLifetime is how long an ID token is reused when its
+// expiration time cannot be parsed. Tokens issued by the metadata server are
+// valid for an hour.
+const idTokenFallbackLifetime = 5 * time.Minute
+
+// idTokenExpiry returns the expiration time of an ID token, a JWT. The token
+// is not verified.
+func idTokenExpiry(idToken string) time.Time {
+	fallback := time.Now().Add(idTokenFallbackLifetime)
+
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return fallback
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fallback
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return fallback
+	}
+
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,190 @@
+package otelcfg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+)
+
+// Special fields recognized by Cloud Logging in structured logs.
+// https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+const (
+	cloudLoggingSeverityKey       = "severity"
+	cloudLoggingMessageKey        = "message"
+	cloudLoggingTimeKey           = "time"
+	cloudLoggingSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	cloudLoggingTraceKey          = "logging.googleapis.com/trace"
+	cloudLoggingSpanIDKey         = "logging.googleapis.com/spanId"
+	cloudLoggingTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+)
+
+type cloudLoggingExporter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	stopped bool
+}
+
+// CloudLoggingStdoutExporter creates a new log exporter that writes each
+// record to w as a single line of JSON in the Cloud Logging structured logging
+// format, which is ingested by Cloud Logging when written to stdout on Cloud
+// Run. Trace context is written in the logging.googleapis.com/trace fields
+// when the record resource has the cloud.account.id attribute. Records that
+// cannot be encoded as JSON are reported to the global OpenTelemetry error
+// handler and skipped.
+func CloudLoggingStdoutExporter(w io.Writer) sdklog.Exporter {
+	return &cloudLoggingExporter{w: w}
+}
+
+func (e *cloudLoggingExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped {
+		return nil
+	}
+
+	for i := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line, err := json.Marshal(cloudLoggingEntry(&records[i]))
+		if err != nil {
+			// Such as a NaN attribute value. The other records can still be
+			// written.
+			otel.Handle(fmt.Errorf("dropping log record: %w", err))
+			continue
+		}
+
+		if _, err := e.w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *cloudLoggingExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.stopped = true
+	return nil
+}
+
+func (e *cloudLoggingExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func cloudLoggingEntry(r *sdklog.Record) map[string]any {
+	entry := make(map[string]any, 4+r.AttributesLen())
+
+	sourceLocation := make(map[string]any, 3)
+	r.WalkAttributes(func(kv log.KeyValue) bool {
+		switch kv.Key {
+		case string(semconv.CodeFilePathKey):
+			sourceLocation["file"] = kv.Value.AsString()
+		case string(semconv.CodeLineNumberKey):
+			sourceLocation["line"] = strconv.FormatInt(kv.Value.AsInt64(), 10)
+		case string(semconv.CodeFunctionNameKey):
+			sourceLocation["function"] = kv.Value.AsString()
+		default:
+			entry[kv.Key] = logValue(kv.Value)
+		}
+		return true
+	})
+	if len(sourceLocation) > 0 {
+		entry[cloudLoggingSourceLocationKey] = sourceLocation
+	}
+
+	ts := r.Timestamp()
+	if ts.IsZero() {
+		ts = r.ObservedTimestamp()
+	}
+	entry[cloudLoggingTimeKey] = ts.Format(time.RFC3339Nano)
+	entry[cloudLoggingSeverityKey] = cloudLoggingSeverity(r.Severity())
+
+	if body := r.Body(); body.Kind() == log.KindString {
+		entry[cloudLoggingMessageKey] = body.AsString()
+	} else if !body.Empty() {
+		entry[cloudLoggingMessageKey] = logValue(body)
+	}
+
+	if traceID := r.TraceID(); traceID.IsValid() {
+		res := r.Resource()
+		if projectID, ok := res.Set().Value(semconv.CloudAccountIDKey); ok {
+			entry[cloudLoggingTraceKey] = "projects/" + projectID.AsString() + "/traces/" + traceID.String()
+			entry[cloudLoggingSpanIDKey] = r.SpanID().String()
+			entry[cloudLoggingTraceSampledKey] = r.TraceFlags().IsSampled()
+		}
+	}
+
+	return entry
+}
+
+// cloudLoggingSeverity maps an OpenTelemetry severity number to a Cloud
+// Logging severity.
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#logseverity
+func cloudLoggingSeverity(s log.Severity) string {
+	switch {
+	case s >= log.SeverityFatal4:
+		return "EMERGENCY"
+	case s >= log.SeverityFatal2:
+		return "ALERT"
+	case s >= log.SeverityFatal1:
+		return "CRITICAL"
+	case s >= log.SeverityError1:
+		return "ERROR"
+	case s >= log.SeverityWarn1:
+		return "WARNING"
+	case s >= log.SeverityInfo2:
+		return "NOTICE"
+	case s >= log.SeverityInfo1:
+		return "INFO"
+	case s >= log.SeverityDebug1:
+		return "DEBUG"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// logValue converts a log value to a value that can be encoded as JSON.
+func logValue(v log.Value) any {
+	switch v.Kind() {
+	case log.KindBool:
+		return v.AsBool()
+	case log.KindInt64:
+		return v.AsInt64()
+	case log.KindFloat64:
+		return v.AsFloat64()
+	case log.KindString:
+		return v.AsString()
+	case log.KindBytes:
+		return v.AsBytes()
+	case log.KindSlice:
+		vals := v.AsSlice()
+		s := make([]any, len(vals))
+		for i, val := range vals {
+			s[i] = logValue(val)
+		}
+		return s
+	case log.KindMap:
+		kvs := v.AsMap()
+		m := make(map[string]any, len(kvs))
+		for _, kv := range kvs {
+			m[kv.Key] = logValue(kv.Value)
+		}
+		return m
+	default:
+		return nil
+	}
+}
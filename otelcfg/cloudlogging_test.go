@@ -0,0 +1,40 @@
+package otelcfg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestCloudLoggingExporterSkipsInvalidRecords(t *testing.T) {
+	var valid, invalid, last sdklog.Record
+	valid.SetBody(log.StringValue("first"))
+	invalid.SetBody(log.StringValue("invalid"))
+	invalid.AddAttributes(log.Float64("ratio", math.NaN()))
+	last.SetBody(log.StringValue("last"))
+
+	var b bytes.Buffer
+	exp := CloudLoggingStdoutExporter(&b)
+	if err := exp.Export(context.Background(), []sdklog.Record{valid, invalid, last}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	var messages []string
+	dec := json.NewDecoder(&b)
+	for dec.More() {
+		var entry map[string]any
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("invalid entry: %v", err)
+		}
+		messages = append(messages, entry[cloudLoggingMessageKey].(string))
+	}
+
+	if len(messages) != 2 || messages[0] != "first" || messages[1] != "last" {
+		t.Errorf("exported %q, want [first last]", messages)
+	}
+}
@@ -0,0 +1,133 @@
+package otelcfg
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/joaopenteado/runcfg"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+)
+
+// GCPCloudRunJobTaskCountKey is the attribute key for the total number of
+// tasks in a Cloud Run job execution. It has no semantic conventions
+// equivalent yet.
+const GCPCloudRunJobTaskCountKey = attribute.Key("gcp.cloud_run.job.task_count")
+
+// detectorMetadataFields are the metadata fields fetched by CloudRunDetector.
+const detectorMetadataFields = runcfg.MetadataProjectID | runcfg.MetadataRegion | runcfg.MetadataZone | runcfg.MetadataInstanceID
+
+// CloudRunDetector is a [resource.Detector] for Cloud Run services and jobs,
+// to be composed with other detectors using resource.New and
+// resource.WithDetectors.
+//
+// It emits every attribute NewServiceResource and NewJobResource do, plus
+// service.name, service.version, service.instance.id, cloud.availability_zone
+// and, for jobs, gcp.cloud_run.job.task_count. For jobs, service.version is
+// set to the execution name.
+//
+// Configuration not set in the detector is loaded with runcfg.LoadService,
+// runcfg.LoadJob and runcfg.LoadMetadata. If neither Service nor Job are set,
+// the workload is detected by the K_SERVICE and CLOUD_RUN_JOB environment
+// variables, and an empty resource is returned outside of Cloud Run.
+//
+// Attributes whose values are unknown are omitted. If the metadata server
+// cannot be reached, the values available from the environment are used and
+// an error wrapping resource.ErrPartialResource is returned along with the
+// resource.
+type CloudRunDetector struct {
+	// Service is the configuration of the Cloud Run service. Takes
+	// precedence over Job.
+	Service *runcfg.Service
+
+	// Job is the configuration of the Cloud Run job.
+	Job *runcfg.Job
+
+	// Metadata is the information from the metadata server.
+	Metadata *runcfg.Metadata
+}
+
+var _ resource.Detector = CloudRunDetector{}
+
+// Detect returns the resource of the Cloud Run service or job.
+func (d CloudRunDetector) Detect(ctx context.Context) (*resource.Resource, error) {
+	res, _, err := d.detect(ctx)
+	return res, err
+}
+
+// detect returns the resource of the Cloud Run service or job along with the
+// metadata it was built from, which is nil outside of Cloud Run.
+func (d CloudRunDetector) detect(ctx context.Context) (*resource.Resource, *runcfg.Metadata, error) {
+	svc, job := d.Service, d.Job
+	if svc == nil && job == nil {
+		var err error
+		switch {
+		case os.Getenv("K_SERVICE") != "":
+			svc, err = runcfg.LoadService()
+		case os.Getenv("CLOUD_RUN_JOB") != "":
+			job, err = runcfg.LoadJob()
+		default:
+			return resource.Empty(), nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var partialErr error
+	md := d.Metadata
+	if md == nil {
+		var err error
+		md, err = runcfg.LoadMetadata(ctx, detectorMetadataFields)
+		if err != nil {
+			// Degrade to the values available from the environment and
+			// default options, which never fails without fetching.
+			md, _ = runcfg.LoadMetadata(ctx, runcfg.MetadataNone)
+			partialErr = err
+		}
+	}
+
+	// https://github.com/open-telemetry/opentelemetry-go-contrib/blob/f368d047b7c605a7805094537a6922db36eabcdc/detectors/gcp/detector.go#L35
+	attrs := []attribute.KeyValue{
+		semconv.CloudProviderGCP,
+		semconv.CloudPlatformGCPCloudRun,
+	}
+	attrs = appendString(attrs, semconv.CloudAccountIDKey, md.ProjectID)
+	attrs = appendString(attrs, semconv.CloudRegionKey, md.Region)
+	attrs = appendString(attrs, semconv.CloudAvailabilityZoneKey, md.Zone)
+	attrs = appendString(attrs, semconv.FaaSInstanceKey, md.InstanceID)
+	attrs = appendString(attrs, semconv.ServiceInstanceIDKey, md.InstanceID)
+
+	if svc != nil {
+		attrs = appendString(attrs, semconv.FaaSNameKey, svc.Name)
+		attrs = appendString(attrs, semconv.FaaSVersionKey, svc.Revision)
+		attrs = appendString(attrs, semconv.ServiceNameKey, svc.Name)
+		attrs = appendString(attrs, semconv.ServiceVersionKey, svc.Revision)
+	} else {
+		attrs = appendString(attrs, semconv.FaaSNameKey, job.Name)
+		attrs = appendString(attrs, semconv.ServiceNameKey, job.Name)
+		attrs = appendString(attrs, semconv.ServiceVersionKey, job.Execution)
+		attrs = appendString(attrs, semconv.GCPCloudRunJobExecutionKey, job.Execution)
+		attrs = append(attrs,
+			semconv.GCPCloudRunJobTaskIndex(int(job.TaskIndex)),
+			GCPCloudRunJobTaskCountKey.Int(int(job.TaskCount)),
+		)
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+	if partialErr != nil {
+		return res, md, fmt.Errorf("%w: %w", resource.ErrPartialResource, partialErr)
+	}
+
+	return res, md, nil
+}
+
+// appendString appends the attribute to attrs only if val is not empty.
+func appendString(attrs []attribute.KeyValue, key attribute.Key, val string) []attribute.KeyValue {
+	if val == "" {
+		return attrs
+	}
+	return append(attrs, key.String(val))
+}
@@ -0,0 +1,134 @@
+package otelcfg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joaopenteado/runcfg"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+)
+
+// unreachableMetadataServer makes the metadata server unreachable for the
+// duration of the test.
+func unreachableMetadataServer(t *testing.T) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	t.Setenv("GCE_METADATA_HOST", srv.Listener.Addr().String())
+}
+
+// clearCloudRunEnv unsets the environment variables read by CloudRunDetector
+// for the duration of the test.
+func clearCloudRunEnv(t *testing.T) {
+	t.Helper()
+
+	for _, name := range []string{"K_SERVICE", "K_REVISION", "K_CONFIGURATION", "PORT",
+		"CLOUD_RUN_JOB", "CLOUD_RUN_EXECUTION", "CLOUD_RUN_TASK_INDEX", "CLOUD_RUN_TASK_COUNT"} {
+		t.Setenv(name, "")
+	}
+	for _, names := range [][]string{runcfg.EnvProjectID, runcfg.EnvProjectNumber, runcfg.EnvRegion,
+		runcfg.EnvInstanceID, runcfg.EnvServiceAccountEmail, runcfg.EnvZone} {
+		for _, name := range names {
+			t.Setenv(name, "")
+		}
+	}
+}
+
+func TestCloudRunDetector(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		detector    CloudRunDetector
+		wantAttrs   []attribute.KeyValue
+		wantPartial bool
+		wantEmpty   bool
+	}{
+		{
+			name:      "outside of Cloud Run",
+			wantEmpty: true,
+		},
+		{
+			name: "service",
+			env:  map[string]string{"K_SERVICE": "api", "K_REVISION": "api-00001"},
+			detector: CloudRunDetector{
+				Metadata: &runcfg.Metadata{ProjectID: "project", Region: "us-central1", Zone: "us-central1-a", InstanceID: "instance"},
+			},
+			wantAttrs: []attribute.KeyValue{
+				semconv.CloudPlatformGCPCloudRun,
+				semconv.CloudAccountID("project"),
+				semconv.CloudRegion("us-central1"),
+				semconv.CloudAvailabilityZone("us-central1-a"),
+				semconv.ServiceInstanceID("instance"),
+				semconv.ServiceName("api"),
+				semconv.ServiceVersion("api-00001"),
+				semconv.FaaSVersion("api-00001"),
+			},
+		},
+		{
+			name: "job",
+			detector: CloudRunDetector{
+				Job:      &runcfg.Job{Name: "batch", Execution: "batch-abc", TaskIndex: 2, TaskCount: 3},
+				Metadata: &runcfg.Metadata{ProjectID: "project"},
+			},
+			wantAttrs: []attribute.KeyValue{
+				semconv.ServiceName("batch"),
+				semconv.ServiceVersion("batch-abc"),
+				semconv.GCPCloudRunJobExecution("batch-abc"),
+				semconv.GCPCloudRunJobTaskIndex(2),
+				GCPCloudRunJobTaskCountKey.Int(3),
+			},
+		},
+		{
+			name: "metadata server unreachable",
+			env: map[string]string{
+				"K_SERVICE":               "api",
+				"GOOGLE_CLOUD_PROJECT_ID": "env-project",
+				"GOOGLE_CLOUD_REGION":     "europe-west1",
+			},
+			wantAttrs: []attribute.KeyValue{
+				semconv.CloudAccountID("env-project"),
+				semconv.CloudRegion("europe-west1"),
+				semconv.ServiceName("api"),
+			},
+			wantPartial: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearCloudRunEnv(t)
+			unreachableMetadataServer(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			res, err := tt.detector.Detect(context.Background())
+			if partial := errors.Is(err, resource.ErrPartialResource); partial != tt.wantPartial {
+				t.Fatalf("Detect() error = %v, want partial %v", err, tt.wantPartial)
+			}
+			if err != nil && !tt.wantPartial {
+				t.Fatalf("Detect() error = %v", err)
+			}
+
+			if tt.wantEmpty {
+				if res.Len() != 0 {
+					t.Errorf("Detect() = %v, want an empty resource", res)
+				}
+				return
+			}
+
+			for _, want := range tt.wantAttrs {
+				got, ok := res.Set().Value(want.Key)
+				if !ok || got != want.Value {
+					t.Errorf("%s = %v, want %v", want.Key, got.Emit(), want.Value.Emit())
+				}
+			}
+		})
+	}
+}
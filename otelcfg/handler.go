@@ -0,0 +1,150 @@
+package otelcfg
+
+import (
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/joaopenteado/runcfg/health"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+type handlerConfig struct {
+	revision      string
+	instanceID    string
+	logger        *zerolog.Logger
+	excludedPaths []string
+	otelhttpOpts  []otelhttp.Option
+}
+
+type HandlerOption = option[handlerConfig]
+
+// WithRevision sets the revision added to server spans as the faas.version
+// attribute. Defaults to the K_REVISION environment variable.
+func WithRevision(revision string) HandlerOption {
+	return optionFunc[handlerConfig](func(cfg *handlerConfig) {
+		cfg.revision = revision
+	})
+}
+
+// WithInstanceID sets the instance ID added to server spans as the
+// faas.instance attribute, such as Telemetry.Metadata.InstanceID. Omitted by
+// default, as it requires querying the metadata server.
+func WithInstanceID(instanceID string) HandlerOption {
+	return optionFunc[handlerConfig](func(cfg *handlerConfig) {
+		cfg.instanceID = instanceID
+	})
+}
+
+// WithLogger adds logger to the request context, bound to the context of the
+// server span, the same way as zerologcfg.Handler, so that logs are
+// correlated with the span.
+func WithLogger(logger zerolog.Logger) HandlerOption {
+	return optionFunc[handlerConfig](func(cfg *handlerConfig) {
+		cfg.logger = &logger
+	})
+}
+
+// WithExcludedPaths sets the paths of requests that are not instrumented.
+// Defaults to the startup and liveness probe paths used by health.Registry.
+func WithExcludedPaths(paths ...string) HandlerOption {
+	return optionFunc[handlerConfig](func(cfg *handlerConfig) {
+		cfg.excludedPaths = paths
+	})
+}
+
+// WithOtelHTTPOptions sets additional options for otelhttp.NewHandler.
+func WithOtelHTTPOptions(opts ...otelhttp.Option) HandlerOption {
+	return optionFunc[handlerConfig](func(cfg *handlerConfig) {
+		cfg.otelhttpOpts = append(cfg.otelhttpOpts, opts...)
+	})
+}
+
+// Handler instruments next with otelhttp following Cloud Run conventions:
+//
+//   - Spans are named after the matched route pattern of http.ServeMux, and
+//     the route is recorded as the http.route attribute of spans and metrics.
+//   - Requests to the startup and liveness probe paths are not instrumented.
+//   - The revision and instance ID are added as span attributes.
+//   - The logger set with WithLogger is added to the request context inside
+//     the server span.
+//
+// The request duration is recorded by otelhttp in the
+// http.server.request.duration histogram, with the buckets of
+// HTTPServerDurationView when the meter provider is set up by
+// SetupMeterProvider.
+//
+// Handler should be the outermost middleware, so that the server span covers
+// all others.
+func Handler(next http.Handler, opts ...HandlerOption) http.Handler {
+	cfg := handlerConfig{
+		revision:      os.Getenv("K_REVISION"),
+		excludedPaths: []string{health.StartupPath, health.LivenessPath},
+	}
+
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	var attrs []attribute.KeyValue
+	attrs = appendString(attrs, semconv.FaaSVersionKey, cfg.revision)
+	attrs = appendString(attrs, semconv.FaaSInstanceKey, cfg.instanceID)
+
+	mux, _ := next.(*http.ServeMux)
+
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.logger != nil {
+			ctx := r.Context()
+			logger := cfg.logger.With().Ctx(ctx).Logger()
+			r = r.WithContext(logger.WithContext(ctx))
+		}
+
+		next.ServeHTTP(w, r)
+
+		// http.ServeMux sets the pattern of the request it routes, so it is
+		// only known after serving when the span name formatter could not
+		// resolve it.
+		if route := patternRoute(r.Pattern); route != "" {
+			span := oteltrace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+			if labeler, ok := otelhttp.LabelerFromContext(r.Context()); ok {
+				labeler.Add(semconv.HTTPRoute(route))
+			}
+		}
+	})
+
+	otelOpts := []otelhttp.Option{
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !slices.Contains(cfg.excludedPaths, r.URL.Path)
+		}),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if mux != nil {
+				if _, pattern := mux.Handler(r); pattern != "" {
+					return r.Method + " " + patternRoute(pattern)
+				}
+			}
+			return r.Method
+		}),
+	}
+	if len(attrs) > 0 {
+		otelOpts = append(otelOpts, otelhttp.WithSpanOptions(oteltrace.WithAttributes(attrs...)))
+	}
+	otelOpts = append(otelOpts, cfg.otelhttpOpts...)
+
+	return otelhttp.NewHandler(h, "", otelOpts...)
+}
+
+// patternRoute returns the path of an http.ServeMux pattern, in the form
+// [METHOD ][HOST]/[PATH], without the method and host.
+func patternRoute(pattern string) string {
+	if i := strings.IndexByte(pattern, '/'); i >= 0 {
+		return pattern[i:]
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package otelcfg
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/contrib/exporters/autoexport"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+const (
+	// cloudLoggingEndpointURL is the Google Cloud Telemetry endpoint for logs.
+	cloudLoggingEndpointURL = "https://telemetry.googleapis.com:443/v1/logs"
+
+	// cloudLoggingScope is the OAuth2 scope required to write logs.
+	cloudLoggingScope = "https://www.googleapis.com/auth/logging.write"
+)
+
+type loggerProviderConfig struct {
+	exporter                 sdklog.Exporter
+	loggerProviderOpts       []sdklog.LoggerProviderOption
+	disableResourceDetection bool
+}
+
+type LoggerProviderOption = option[loggerProviderConfig]
+
+// WithLogExporter sets the log exporter for the logger provider.
+// By default, the exporter will be configured by autoexport, falling back to
+// the CloudLoggingStdoutExporter if no environment-specific exporter is
+// detected.
+func WithLogExporter(exporter sdklog.Exporter) LoggerProviderOption {
+	return optionFunc[loggerProviderConfig](func(cfg *loggerProviderConfig) {
+		cfg.exporter = exporter
+	})
+}
+
+// WithLoggerResourceDetection sets whether the logger provider resource is
+// detected automatically, the same way as WithTracerResourceDetection. It is
+// enabled by default. A resource set with WithLoggerProviderOptions always
+// takes precedence.
+func WithLoggerResourceDetection(enabled bool) LoggerProviderOption {
+	return optionFunc[loggerProviderConfig](func(cfg *loggerProviderConfig) {
+		cfg.disableResourceDetection = !enabled
+	})
+}
+
+// WithLoggerProviderOptions sets additional options for the logger provider.
+func WithLoggerProviderOptions(opts ...sdklog.LoggerProviderOption) LoggerProviderOption {
+	return optionFunc[loggerProviderConfig](func(cfg *loggerProviderConfig) {
+		cfg.loggerProviderOpts = append(cfg.loggerProviderOpts, opts...)
+	})
+}
+
+// SetupLoggerProvider creates a logger provider and sets it as the global
+// logger provider. Use NewZerologWriter to bridge zerolog loggers to it.
+func SetupLoggerProvider(ctx context.Context, opts ...LoggerProviderOption) (*sdklog.LoggerProvider, error) {
+	var cfg loggerProviderConfig
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	lpOpts, err := providerOptions(ctx, !cfg.disableResourceDetection, sdklog.WithResource, cfg.loggerProviderOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.exporter == nil {
+		// Create a new log exporter using autoexport, which will automatically
+		// detect and use the appropriate exporter based on the environment.
+		// If no environment-specific exporter is detected, it will fall back to
+		// writing to stdout in the Cloud Logging structured format, which is
+		// ingested by Cloud Logging without any additional setup on Cloud Run.
+		exporter, err := autoexport.NewLogExporter(ctx, autoexport.WithFallbackLogExporter(
+			func(ctx context.Context) (sdklog.Exporter, error) {
+				return CloudLoggingStdoutExporter(os.Stdout), nil
+			},
+		))
+		if err != nil {
+			return nil, err
+		}
+		cfg.exporter = exporter
+		// Shutting down the logger provider will also shut down the log
+		// exporter. No need to explicitly call the shutdown function.
+	}
+
+	lp := sdklog.NewLoggerProvider(append([]sdklog.LoggerProviderOption{
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(cfg.exporter)),
+	}, lpOpts...)...)
+
+	// Set the logger provider as the global logger provider.
+	global.SetLoggerProvider(lp)
+
+	return lp, nil
+}
+
+// CloudLoggingOTLPExporter creates a new log exporter for the Google Cloud
+// Telemetry OpenTelemetry Protocol (OTLP) endpoint, exporting logs over gRPC
+// using Application Default Credentials. Use NewCloudLoggingOTLPExporter to
+// configure it.
+func CloudLoggingOTLPExporter(ctx context.Context) (sdklog.Exporter, error) {
+	return NewCloudLoggingOTLPExporter(ctx)
+}
+
+// NewCloudLoggingOTLPExporter creates a new log exporter for the Google Cloud
+// Telemetry OpenTelemetry Protocol (OTLP) endpoint. It accepts the same
+// options as NewCloudTraceOTLPExporter.
+func NewCloudLoggingOTLPExporter(ctx context.Context, opts ...OTLPExporterOption) (sdklog.Exporter, error) {
+	cfg, err := newOTLPExporterConfig(ctx, cloudLoggingEndpointURL, cloudLoggingScope, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.protocol == ProtocolHTTPProtobuf {
+		httpOpts := []otlploghttp.Option{
+			otlploghttp.WithEndpointURL(cfg.endpointURL),
+			otlploghttp.WithHTTPClient(cfg.httpClient()),
+		}
+		if cfg.insecure {
+			httpOpts = append(httpOpts, otlploghttp.WithInsecure())
+		}
+		if len(cfg.headers) > 0 {
+			httpOpts = append(httpOpts, otlploghttp.WithHeaders(cfg.headers))
+		}
+		if cfg.gzip {
+			httpOpts = append(httpOpts, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+		}
+		return otlploghttp.New(ctx, httpOpts...)
+	}
+
+	grpcOpts := []otlploggrpc.Option{
+		otlploggrpc.WithEndpointURL(cfg.endpointURL),
+	}
+	for _, opt := range cfg.grpcDialOptions() {
+		grpcOpts = append(grpcOpts, otlploggrpc.WithDialOption(opt))
+	}
+	if cfg.insecure {
+		grpcOpts = append(grpcOpts, otlploggrpc.WithInsecure())
+	}
+	if len(cfg.headers) > 0 {
+		grpcOpts = append(grpcOpts, otlploggrpc.WithHeaders(cfg.headers))
+	}
+	if cfg.gzip {
+		grpcOpts = append(grpcOpts, otlploggrpc.WithCompressor("gzip"))
+	}
+	return otlploggrpc.New(ctx, grpcOpts...)
+}
@@ -2,17 +2,57 @@ package otelcfg
 
 import (
 	"context"
+	"strconv"
+	"sync"
+	"time"
 
 	"go.opentelemetry.io/contrib/exporters/autoexport"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
 
 	mexporter "github.com/GoogleCloudPlatform/opentelemetry-operations-go/exporter/metric"
 )
 
+const (
+	// cloudMonitoringEndpointURL is the Google Cloud Telemetry endpoint for
+	// metrics.
+	cloudMonitoringEndpointURL = "https://telemetry.googleapis.com:443/v1/metrics"
+
+	// cloudMonitoringScope is the OAuth2 scope required to write metrics.
+	cloudMonitoringScope = "https://www.googleapis.com/auth/monitoring.write"
+
+	// CloudMonitoringMinWriteInterval is the minimum interval between writes
+	// to the same time series accepted by Cloud Monitoring.
+	// https://cloud.google.com/monitoring/quotas#custom_metrics_quotas
+	CloudMonitoringMinWriteInterval = 5 * time.Second
+)
+
+// HTTPServerDurationView sets the buckets of the http.server.request.duration
+// histogram, in seconds, to cover requests up to the maximum Cloud Run request
+// timeout of one hour. The default buckets of otelhttp stop at 10 seconds.
+// SetupMeterProvider registers it by default.
+var HTTPServerDurationView = metric.NewView(
+	metric.Instrument{Name: "http.server.request.duration"},
+	metric.Stream{Aggregation: metric.AggregationExplicitBucketHistogram{
+		Boundaries: []float64{
+			0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+			30, 60, 120, 300, 600, 1800, 3600,
+		},
+	}},
+)
+
 type meterProviderConfig struct {
-	reader        metric.Reader
-	metricOptions []metric.Option
+	reader                   metric.Reader
+	fallbackOTLP             bool
+	fallbackOpts             []CloudMonitoringMetricReaderOption
+	metricOptions            []metric.Option
+	disableResourceDetection bool
 }
 
 type MeterProviderOption = option[meterProviderConfig]
@@ -24,6 +64,39 @@ func WithReader(reader metric.Reader) MeterProviderOption {
 	})
 }
 
+// WithCloudMonitoringFallback sets the options of the
+// CloudMonitoringMetricReader used when no reader is set and autoexport falls
+// back to it.
+func WithCloudMonitoringFallback(opts ...CloudMonitoringMetricReaderOption) MeterProviderOption {
+	return optionFunc[meterProviderConfig](func(cfg *meterProviderConfig) {
+		cfg.fallbackOTLP = false
+		cfg.fallbackOpts = opts
+	})
+}
+
+// WithCloudMonitoringOTLPFallback makes autoexport fall back to the
+// CloudMonitoringOTLPMetricReader, configured with opts, instead of the
+// CloudMonitoringMetricReader when no reader is set.
+func WithCloudMonitoringOTLPFallback(opts ...CloudMonitoringMetricReaderOption) MeterProviderOption {
+	return optionFunc[meterProviderConfig](func(cfg *meterProviderConfig) {
+		cfg.fallbackOTLP = true
+		cfg.fallbackOpts = opts
+	})
+}
+
+// WithMeterResourceDetection sets whether the meter provider resource is
+// detected automatically. When enabled, which is the default, the resource of
+// the Cloud Run service or job is detected with CloudRunDetector and merged
+// with the SDK default resource and the OTEL_RESOURCE_ATTRIBUTES and
+// OTEL_SERVICE_NAME environment variables, with the environment taking
+// precedence. Nothing is detected outside of Cloud Run.
+// A resource set with WithMetricOptions always takes precedence.
+func WithMeterResourceDetection(enabled bool) MeterProviderOption {
+	return optionFunc[meterProviderConfig](func(cfg *meterProviderConfig) {
+		cfg.disableResourceDetection = !enabled
+	})
+}
+
 // WithMetricOptions sets the options for the meter provider.
 func WithMetricOptions(opts ...metric.Option) MeterProviderOption {
 	return optionFunc[meterProviderConfig](func(cfg *meterProviderConfig) {
@@ -40,10 +113,18 @@ func SetupMeterProvider(ctx context.Context, opts ...MeterProviderOption) (*metr
 		opt.apply(&cfg)
 	}
 
+	mpOpts, err := providerOptions(ctx, !cfg.disableResourceDetection, metric.WithResource, cfg.metricOptions)
+	if err != nil {
+		return nil, err
+	}
+
 	if cfg.reader == nil {
 		reader, err := autoexport.NewMetricReader(ctx, autoexport.WithFallbackMetricReader(
 			func(ctx context.Context) (metric.Reader, error) {
-				return CloudMonitoringMetricReader(ctx)
+				if cfg.fallbackOTLP {
+					return CloudMonitoringOTLPMetricReader(ctx, cfg.fallbackOpts...)
+				}
+				return CloudMonitoringMetricReader(ctx, cfg.fallbackOpts...)
 			},
 		))
 		if err != nil {
@@ -52,11 +133,10 @@ func SetupMeterProvider(ctx context.Context, opts ...MeterProviderOption) (*metr
 		cfg.reader = reader
 	}
 
-	mpOpts := make([]metric.Option, 1, 1+len(cfg.metricOptions))
-	mpOpts[0] = metric.WithReader(cfg.reader)
-	mpOpts = append(mpOpts, cfg.metricOptions...)
-
-	mp := metric.NewMeterProvider(mpOpts...)
+	mp := metric.NewMeterProvider(append([]metric.Option{
+		metric.WithReader(cfg.reader),
+		metric.WithView(HTTPServerDurationView),
+	}, mpOpts...)...)
 
 	otel.SetMeterProvider(mp)
 
@@ -65,7 +145,11 @@ func SetupMeterProvider(ctx context.Context, opts ...MeterProviderOption) (*metr
 
 type cloudMonitoringMetricReaderConfig struct {
 	exportOptions []mexporter.Option
+	otlpOptions   []OTLPExporterOption
 	readerOptions []metric.PeriodicReaderOption
+	minInterval   time.Duration
+	taskID        string
+	disableTask   bool
 }
 
 type CloudMonitoringMetricReaderOption = option[cloudMonitoringMetricReaderConfig]
@@ -77,6 +161,14 @@ func WithExporterOptions(opts ...mexporter.Option) CloudMonitoringMetricReaderOp
 	})
 }
 
+// WithOTLPExporterOptions sets the options for the OTLP metric exporter used
+// by CloudMonitoringOTLPMetricReader.
+func WithOTLPExporterOptions(opts ...OTLPExporterOption) CloudMonitoringMetricReaderOption {
+	return optionFunc[cloudMonitoringMetricReaderConfig](func(cfg *cloudMonitoringMetricReaderConfig) {
+		cfg.otlpOptions = append(cfg.otlpOptions, opts...)
+	})
+}
+
 // WithReaderOptions sets the options for the Cloud Monitoring metric reader.
 func WithReaderOptions(opts ...metric.PeriodicReaderOption) CloudMonitoringMetricReaderOption {
 	return optionFunc[cloudMonitoringMetricReaderConfig](func(cfg *cloudMonitoringMetricReaderConfig) {
@@ -84,10 +176,55 @@ func WithReaderOptions(opts ...metric.PeriodicReaderOption) CloudMonitoringMetri
 	})
 }
 
+// WithMinWriteInterval sets the minimum interval between exports. An export
+// attempted sooner, such as the final export when shutting down a short-lived
+// job, waits for the interval to elapse or its context to be done, whichever
+// happens first. Defaults to CloudMonitoringMinWriteInterval. Set it to zero
+// to disable waiting, such as when exporting to a local OTLP receiver.
+func WithMinWriteInterval(interval time.Duration) CloudMonitoringMetricReaderOption {
+	return optionFunc[cloudMonitoringMetricReaderConfig](func(cfg *cloudMonitoringMetricReaderConfig) {
+		cfg.minInterval = interval
+	})
+}
+
+// WithTaskID sets the service.instance.id resource attribute of exported
+// metrics, which Cloud Monitoring maps to the task_id label of the generic_task
+// monitored resource. Every writer of the same metric must have a distinct
+// task ID, or its points are rejected as written too frequently. On Cloud Run,
+// it defaults to the instance ID or, for jobs where it is unknown, to the
+// execution name and task index.
+func WithTaskID(taskID string) CloudMonitoringMetricReaderOption {
+	return optionFunc[cloudMonitoringMetricReaderConfig](func(cfg *cloudMonitoringMetricReaderConfig) {
+		cfg.taskID = taskID
+	})
+}
+
+// WithTaskMapping sets whether the task ID of exported metrics is set as
+// described in WithTaskID. It is enabled by default. When disabled, the
+// resource is exported as is.
+func WithTaskMapping(enabled bool) CloudMonitoringMetricReaderOption {
+	return optionFunc[cloudMonitoringMetricReaderConfig](func(cfg *cloudMonitoringMetricReaderConfig) {
+		cfg.disableTask = !enabled
+	})
+}
+
+// wrap wraps exporter with the task mapping and minimum write interval.
+func (cfg *cloudMonitoringMetricReaderConfig) wrap(exporter metric.Exporter) metric.Exporter {
+	if !cfg.disableTask {
+		exporter = &taskExporter{Exporter: exporter, taskID: cfg.taskID}
+	}
+	return withMinInterval(exporter, cfg.minInterval)
+}
+
 // CloudMonitoringMetricReader creates a new Cloud Monitoring metric reader.
+// Exports are spaced at least CloudMonitoringMinWriteInterval apart, unless
+// changed with WithMinWriteInterval. On Cloud Run, metrics are written to the
+// generic_task monitored resource with a task_id unique to the instance or job
+// task, as described in WithTaskID.
 func CloudMonitoringMetricReader(ctx context.Context, opts ...CloudMonitoringMetricReaderOption) (metric.Reader, error) {
 	cfg := cloudMonitoringMetricReaderConfig{
 		exportOptions: []mexporter.Option{},
+		minInterval:   CloudMonitoringMinWriteInterval,
 	}
 
 	for _, opt := range opts {
@@ -99,5 +236,180 @@ func CloudMonitoringMetricReader(ctx context.Context, opts ...CloudMonitoringMet
 		return nil, err
 	}
 
-	return metric.NewPeriodicReader(exporter, cfg.readerOptions...), nil
+	return metric.NewPeriodicReader(cfg.wrap(exporter), cfg.readerOptions...), nil
+}
+
+// CloudMonitoringOTLPMetricReader creates a new metric reader exporting to the
+// Google Cloud Telemetry OpenTelemetry Protocol (OTLP) endpoint. By default,
+// metrics are exported over gRPC using Application Default Credentials; use
+// WithOTLPExporterOptions to change the endpoint, protocol or credentials,
+// such as to export to a local OTLP receiver. As with
+// CloudMonitoringMetricReader, exports are spaced at least
+// CloudMonitoringMinWriteInterval apart and the task ID is set as described
+// in WithTaskID.
+//
+// Metrics are always exported with cumulative temporality, as Cloud Monitoring
+// rejects delta sums and histograms, regardless of the
+// OTEL_EXPORTER_OTLP_METRICS_TEMPORALITY_PREFERENCE environment variable.
+// https://cloud.google.com/stackdriver/docs/reference/telemetry/overview
+func CloudMonitoringOTLPMetricReader(ctx context.Context, opts ...CloudMonitoringMetricReaderOption) (metric.Reader, error) {
+	cfg := cloudMonitoringMetricReaderConfig{
+		minInterval: CloudMonitoringMinWriteInterval,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	exporter, err := cloudMonitoringOTLPExporter(ctx, cfg.otlpOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.NewPeriodicReader(cfg.wrap(exporter), cfg.readerOptions...), nil
+}
+
+func cloudMonitoringOTLPExporter(ctx context.Context, opts []OTLPExporterOption) (metric.Exporter, error) {
+	cfg, err := newOTLPExporterConfig(ctx, cloudMonitoringEndpointURL, cloudMonitoringScope, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.protocol == ProtocolHTTPProtobuf {
+		httpOpts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpointURL(cfg.endpointURL),
+			otlpmetrichttp.WithHTTPClient(cfg.httpClient()),
+			otlpmetrichttp.WithTemporalitySelector(cumulativeTemporality),
+			otlpmetrichttp.WithAggregationSelector(metric.DefaultAggregationSelector),
+		}
+		if cfg.insecure {
+			httpOpts = append(httpOpts, otlpmetrichttp.WithInsecure())
+		}
+		if len(cfg.headers) > 0 {
+			httpOpts = append(httpOpts, otlpmetrichttp.WithHeaders(cfg.headers))
+		}
+		if cfg.gzip {
+			httpOpts = append(httpOpts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		}
+		return otlpmetrichttp.New(ctx, httpOpts...)
+	}
+
+	grpcOpts := []otlpmetricgrpc.Option{
+		otlpmetricgrpc.WithEndpointURL(cfg.endpointURL),
+		otlpmetricgrpc.WithTemporalitySelector(cumulativeTemporality),
+		otlpmetricgrpc.WithAggregationSelector(metric.DefaultAggregationSelector),
+	}
+	for _, opt := range cfg.grpcDialOptions() {
+		grpcOpts = append(grpcOpts, otlpmetricgrpc.WithDialOption(opt))
+	}
+	if cfg.insecure {
+		grpcOpts = append(grpcOpts, otlpmetricgrpc.WithInsecure())
+	}
+	if len(cfg.headers) > 0 {
+		grpcOpts = append(grpcOpts, otlpmetricgrpc.WithHeaders(cfg.headers))
+	}
+	if cfg.gzip {
+		grpcOpts = append(grpcOpts, otlpmetricgrpc.WithCompressor("gzip"))
+	}
+	return otlpmetricgrpc.New(ctx, grpcOpts...)
+}
+
+// cumulativeTemporality selects cumulative temporality for every instrument.
+func cumulativeTemporality(metric.InstrumentKind) metricdata.Temporality {
+	return metricdata.CumulativeTemporality
+}
+
+// minIntervalExporter is a metric exporter that spaces exports at least
+// interval apart, as Cloud Monitoring rejects points written to a time series
+// more frequently than its minimum write interval.
+type minIntervalExporter struct {
+	metric.Exporter
+
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// withMinInterval wraps exporter so that exports are spaced at least interval
+// apart. It returns exporter itself if interval is not positive.
+func withMinInterval(exporter metric.Exporter, interval time.Duration) metric.Exporter {
+	if interval <= 0 {
+		return exporter
+	}
+	return &minIntervalExporter{Exporter: exporter, interval: interval}
+}
+
+func (e *minIntervalExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.last.IsZero() {
+		if wait := e.interval - time.Since(e.last); wait > 0 {
+			t := time.NewTimer(wait)
+			defer t.Stop()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+	}
+
+	err := e.Exporter.Export(ctx, rm)
+	e.last = time.Now()
+	return err
+}
+
+// taskExporter is a metric exporter that sets the service.instance.id
+// attribute of the exported resource to a task ID, so that each Cloud Run
+// instance or job task writes its own time series.
+type taskExporter struct {
+	metric.Exporter
+
+	taskID string
+}
+
+func (e *taskExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	taskID := e.taskID
+	if taskID == "" {
+		taskID = resourceTaskID(rm.Resource)
+	}
+	if taskID == "" {
+		return e.Exporter.Export(ctx, rm)
+	}
+
+	res, err := resource.Merge(rm.Resource, resource.NewSchemaless(semconv.ServiceInstanceID(taskID)))
+	if err != nil {
+		return err
+	}
+
+	// Copied so the resource of the caller is left untouched.
+	mapped := *rm
+	mapped.Resource = res
+	return e.Exporter.Export(ctx, &mapped)
+}
+
+// resourceTaskID returns the task ID of a Cloud Run resource: its
+// service.instance.id or faas.instance attribute or, for jobs, its execution
+// name and task index. It returns an empty string outside of Cloud Run.
+func resourceTaskID(res *resource.Resource) string {
+	set := res.Set()
+	if platform, _ := set.Value(semconv.CloudPlatformKey); platform.AsString() != semconv.CloudPlatformGCPCloudRun.Value.AsString() {
+		return ""
+	}
+
+	for _, key := range []attribute.Key{semconv.ServiceInstanceIDKey, semconv.FaaSInstanceKey} {
+		if v, ok := set.Value(key); ok && v.AsString() != "" {
+			return v.AsString()
+		}
+	}
+
+	execution, ok := set.Value(semconv.GCPCloudRunJobExecutionKey)
+	if !ok || execution.AsString() == "" {
+		return ""
+	}
+	taskIndex, _ := set.Value(semconv.GCPCloudRunJobTaskIndexKey)
+	return execution.AsString() + "/" + strconv.FormatInt(taskIndex.AsInt64(), 10)
 }
@@ -0,0 +1,167 @@
+package otelcfg
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/oauth"
+)
+
+// Protocol is the transport protocol used by OTLP exporters.
+type Protocol string
+
+const (
+	// ProtocolGRPC exports over gRPC.
+	ProtocolGRPC Protocol = "grpc"
+
+	// ProtocolHTTPProtobuf exports over HTTP with protobuf-encoded payloads.
+	ProtocolHTTPProtobuf Protocol = "http/protobuf"
+)
+
+// quotaProjectHeader is the header used to specify the project to be billed
+// and used for quota.
+const quotaProjectHeader = "x-goog-user-project"
+
+type otlpExporterConfig struct {
+	endpointURL       string
+	protocol          Protocol
+	tokenSource       oauth2.TokenSource
+	useMetadataServer bool
+	headers           map[string]string
+	gzip              bool
+	insecure          bool
+}
+
+type OTLPExporterOption = option[otlpExporterConfig]
+
+// WithEndpointURL sets the URL of the OTLP endpoint, such as
+// http://localhost:4317 for a local collector. By default, the Google Cloud
+// Telemetry endpoint of the signal is used.
+func WithEndpointURL(endpointURL string) OTLPExporterOption {
+	return optionFunc[otlpExporterConfig](func(cfg *otlpExporterConfig) {
+		cfg.endpointURL = endpointURL
+	})
+}
+
+// WithProtocol sets the transport protocol. By default, ProtocolGRPC is used.
+// Use ProtocolHTTPProtobuf where gRPC traffic is blocked.
+func WithProtocol(protocol Protocol) OTLPExporterOption {
+	return optionFunc[otlpExporterConfig](func(cfg *otlpExporterConfig) {
+		cfg.protocol = protocol
+	})
+}
+
+// WithTokenSource sets the source of the OAuth2 tokens used to authenticate
+// requests. By default, Application Default Credentials are used.
+func WithTokenSource(ts oauth2.TokenSource) OTLPExporterOption {
+	return optionFunc[otlpExporterConfig](func(cfg *otlpExporterConfig) {
+		cfg.tokenSource = ts
+	})
+}
+
+// WithMetadataServerCredentials authenticates requests with tokens of the
+// default service account fetched directly from the metadata server, skipping
+// the Application Default Credentials lookup.
+func WithMetadataServerCredentials() OTLPExporterOption {
+	return optionFunc[otlpExporterConfig](func(cfg *otlpExporterConfig) {
+		cfg.useMetadataServer = true
+	})
+}
+
+// WithHeaders sets additional headers sent with every export request.
+func WithHeaders(headers map[string]string) OTLPExporterOption {
+	return optionFunc[otlpExporterConfig](func(cfg *otlpExporterConfig) {
+		if cfg.headers == nil {
+			cfg.headers = make(map[string]string, len(headers))
+		}
+		maps.Copy(cfg.headers, headers)
+	})
+}
+
+// WithQuotaProject sets the x-goog-user-project header, specifying the project
+// billed and used for quota, which is required when authenticating with user
+// credentials.
+func WithQuotaProject(projectID string) OTLPExporterOption {
+	return WithHeaders(map[string]string{quotaProjectHeader: projectID})
+}
+
+// WithGzipCompression enables gzip compression of export requests.
+func WithGzipCompression() OTLPExporterOption {
+	return optionFunc[otlpExporterConfig](func(cfg *otlpExporterConfig) {
+		cfg.gzip = true
+	})
+}
+
+// WithInsecure disables transport security and authentication, for use with a
+// local stand-in collector.
+func WithInsecure() OTLPExporterOption {
+	return optionFunc[otlpExporterConfig](func(cfg *otlpExporterConfig) {
+		cfg.insecure = true
+	})
+}
+
+// newOTLPExporterConfig applies opts over the defaults for a signal and
+// resolves the token source for the given OAuth2 scope.
+func newOTLPExporterConfig(ctx context.Context, endpointURL, scope string, opts []OTLPExporterOption) (*otlpExporterConfig, error) {
+	cfg := &otlpExporterConfig{
+		endpointURL: endpointURL,
+		protocol:    ProtocolGRPC,
+	}
+
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+
+	switch cfg.protocol {
+	case ProtocolGRPC, ProtocolHTTPProtobuf:
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol: %q", cfg.protocol)
+	}
+
+	if cfg.insecure || cfg.tokenSource != nil {
+		return cfg, nil
+	}
+
+	if cfg.useMetadataServer {
+		cfg.tokenSource = google.ComputeTokenSource("", scope)
+		return cfg, nil
+	}
+
+	ts, err := google.DefaultTokenSource(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	cfg.tokenSource = ts
+
+	return cfg, nil
+}
+
+// grpcDialOptions returns the dial options authenticating gRPC requests.
+func (cfg *otlpExporterConfig) grpcDialOptions() []grpc.DialOption {
+	if cfg.insecure {
+		return nil
+	}
+
+	return []grpc.DialOption{
+		grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: cfg.tokenSource}),
+	}
+}
+
+// httpClient returns the client authenticating HTTP requests.
+func (cfg *otlpExporterConfig) httpClient() *http.Client {
+	if cfg.insecure {
+		return http.DefaultClient
+	}
+
+	return &http.Client{
+		Transport: &oauth2.Transport{
+			Source: oauth2.ReuseTokenSource(nil, cfg.tokenSource),
+			Base:   http.DefaultTransport,
+		},
+	}
+}
@@ -0,0 +1,162 @@
+package otelcfg
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/contrib/propagators/autoprop"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GCPPropagatorName is the name the propagator returned by NewGCPPropagator is
+// registered with in autoprop by RegisterGCPPropagator. Set
+// OTEL_PROPAGATORS=gcp to select it when the text map propagator is
+// configured by autoprop.
+const GCPPropagatorName = "gcp"
+
+// CloudTraceContextHeader is the legacy header used by Google Cloud to
+// propagate trace context.
+const CloudTraceContextHeader = "X-Cloud-Trace-Context"
+
+var registerGCPPropagator struct {
+	once sync.Once
+	err  error
+}
+
+// RegisterGCPPropagator registers the propagator returned by NewGCPPropagator
+// in autoprop as GCPPropagatorName. SetupTracerProvider calls it when the text
+// map propagator is configured by autoprop, so it only needs to be called
+// before using autoprop directly. Calling it more than once has no effect.
+//
+// Unlike autoprop.RegisterTextMapPropagator, it does not panic if another
+// propagator is already registered with the same name, but returns an error
+// and leaves the other propagator registered.
+func RegisterGCPPropagator() error {
+	r := &registerGCPPropagator
+	r.once.Do(func() {
+		if _, err := autoprop.TextMapPropagator(GCPPropagatorName); err == nil {
+			r.err = fmt.Errorf("a propagator is already registered as %q", GCPPropagatorName)
+			return
+		}
+		autoprop.RegisterTextMapPropagator(GCPPropagatorName, NewGCPPropagator())
+	})
+	return r.err
+}
+
+// CloudTraceContext is a propagator for the X-Cloud-Trace-Context header, in
+// the format TRACE_ID/SPAN_ID;o=OPTIONS. TRACE_ID is a 32-character
+// hexadecimal value, SPAN_ID is the decimal representation of the unsigned
+// span ID and OPTIONS is 1 if the trace is sampled and 0 otherwise.
+// https://cloud.google.com/trace/docs/trace-context#legacy-http-header
+type CloudTraceContext struct{}
+
+var _ propagation.TextMapPropagator = CloudTraceContext{}
+
+// Inject sets the X-Cloud-Trace-Context header from the span context in ctx.
+func (CloudTraceContext) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+
+	spanID := sc.SpanID()
+	sampled := "0"
+	if sc.IsSampled() {
+		sampled = "1"
+	}
+
+	carrier.Set(CloudTraceContextHeader,
+		sc.TraceID().String()+"/"+
+			strconv.FormatUint(binary.BigEndian.Uint64(spanID[:]), 10)+
+			";o="+sampled)
+}
+
+// Extract reads the X-Cloud-Trace-Context header and returns a copy of ctx
+// with the remote span context it carries. If the header is missing or
+// invalid, ctx is returned unchanged.
+func (CloudTraceContext) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	sc, ok := parseCloudTraceContext(carrier.Get(CloudTraceContextHeader))
+	if !ok {
+		return ctx
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Fields returns the keys whose values are set by Inject.
+func (CloudTraceContext) Fields() []string {
+	return []string{CloudTraceContextHeader}
+}
+
+func parseCloudTraceContext(h string) (trace.SpanContext, bool) {
+	traceIDStr, rest, ok := strings.Cut(h, "/")
+	if !ok {
+		return trace.SpanContext{}, false
+	}
+
+	traceID, err := trace.TraceIDFromHex(traceIDStr)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	spanIDStr, opts, _ := strings.Cut(rest, ";")
+	spanIDNum, err := strconv.ParseUint(spanIDStr, 10, 64)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], spanIDNum)
+
+	var flags trace.TraceFlags
+	if opts == "o=1" {
+		flags = trace.FlagsSampled
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	})
+	return sc, sc.IsValid()
+}
+
+type gcpPropagator struct {
+	traceContext      propagation.TraceContext
+	cloudTraceContext CloudTraceContext
+	baggage           propagation.Baggage
+}
+
+// NewGCPPropagator returns a propagator suited for Google Cloud. It extracts
+// the W3C traceparent header first, falling back to X-Cloud-Trace-Context
+// when traceparent is missing or invalid, as is the case for requests coming
+// from some Google Cloud services. Only W3C trace context and baggage are
+// injected.
+func NewGCPPropagator() propagation.TextMapPropagator {
+	return gcpPropagator{}
+}
+
+func (p gcpPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	p.traceContext.Inject(ctx, carrier)
+	p.baggage.Inject(ctx, carrier)
+}
+
+func (p gcpPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	sc := trace.SpanContextFromContext(p.traceContext.Extract(context.Background(), carrier))
+	if !sc.IsValid() {
+		sc = trace.SpanContextFromContext(p.cloudTraceContext.Extract(context.Background(), carrier))
+	}
+	if sc.IsValid() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+	}
+
+	return p.baggage.Extract(ctx, carrier)
+}
+
+func (p gcpPropagator) Fields() []string {
+	return append(p.traceContext.Fields(), p.baggage.Fields()...)
+}
@@ -0,0 +1,140 @@
+package otelcfg
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/contrib/propagators/autoprop"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var testSpanContext = trace.NewSpanContext(trace.SpanContextConfig{
+	TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+	SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	TraceFlags: trace.FlagsSampled,
+	Remote:     true,
+})
+
+func TestGCPPropagatorRoundTrip(t *testing.T) {
+	member, _ := baggage.NewMember("user", "ana")
+	bag, _ := baggage.New(member)
+
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), testSpanContext)
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	p := NewGCPPropagator()
+	carrier := propagation.MapCarrier{}
+	p.Inject(ctx, carrier)
+
+	if carrier.Get(CloudTraceContextHeader) != "" {
+		t.Errorf("injected %s, want only W3C headers", CloudTraceContextHeader)
+	}
+
+	got := p.Extract(context.Background(), carrier)
+	if sc := trace.SpanContextFromContext(got); !sc.Equal(testSpanContext) {
+		t.Errorf("extracted span context %v, want %v", sc, testSpanContext)
+	}
+	if v := baggage.FromContext(got).Member("user").Value(); v != "ana" {
+		t.Errorf("extracted baggage user = %q, want ana", v)
+	}
+}
+
+func TestGCPPropagatorExtract(t *testing.T) {
+	// 4bf92f3577b34da6a3ce929d0e0e4736 is the trace ID of testSpanContext,
+	// and 67667974448284343 its span ID in decimal.
+	const cloudTraceContext = "4bf92f3577b34da6a3ce929d0e0e4736/67667974448284343;o=1"
+
+	tests := []struct {
+		name    string
+		carrier propagation.MapCarrier
+		want    trace.SpanContext
+	}{
+		{
+			name: "traceparent",
+			carrier: propagation.MapCarrier{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			},
+			want: testSpanContext,
+		},
+		{
+			name: "X-Cloud-Trace-Context",
+			carrier: propagation.MapCarrier{
+				CloudTraceContextHeader: cloudTraceContext,
+			},
+			want: testSpanContext,
+		},
+		{
+			name: "invalid traceparent",
+			carrier: propagation.MapCarrier{
+				"traceparent":           "00-invalid",
+				CloudTraceContextHeader: cloudTraceContext,
+			},
+			want: testSpanContext,
+		},
+		{
+			name: "not sampled",
+			carrier: propagation.MapCarrier{
+				CloudTraceContextHeader: "4bf92f3577b34da6a3ce929d0e0e4736/67667974448284343;o=0",
+			},
+			want: testSpanContext.WithTraceFlags(0),
+		},
+		{
+			name: "invalid X-Cloud-Trace-Context",
+			carrier: propagation.MapCarrier{
+				CloudTraceContextHeader: "4bf92f3577b34da6a3ce929d0e0e4736/not-a-number",
+			},
+		},
+		{
+			name:    "none",
+			carrier: propagation.MapCarrier{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// MapCarrier keys are case sensitive, while HTTP headers are
+			// canonicalized.
+			carrier := propagation.MapCarrier{}
+			for k, v := range tt.carrier {
+				carrier.Set(k, v)
+				if k == CloudTraceContextHeader {
+					carrier.Set("x-cloud-trace-context", v)
+				}
+			}
+
+			ctx := NewGCPPropagator().Extract(context.Background(), carrier)
+			if sc := trace.SpanContextFromContext(ctx); !sc.Equal(tt.want) {
+				t.Errorf("extracted span context %v, want %v", sc, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloudTraceContextRoundTrip(t *testing.T) {
+	var p CloudTraceContext
+	carrier := propagation.MapCarrier{}
+	p.Inject(trace.ContextWithRemoteSpanContext(context.Background(), testSpanContext), carrier)
+
+	if got, want := carrier.Get(CloudTraceContextHeader), "4bf92f3577b34da6a3ce929d0e0e4736/67667974448284343;o=1"; got != want {
+		t.Errorf("injected %q, want %q", got, want)
+	}
+
+	ctx := p.Extract(context.Background(), carrier)
+	if sc := trace.SpanContextFromContext(ctx); !sc.Equal(testSpanContext) {
+		t.Errorf("extracted span context %v, want %v", sc, testSpanContext)
+	}
+}
+
+func TestRegisterGCPPropagator(t *testing.T) {
+	for range 2 {
+		if err := RegisterGCPPropagator(); err != nil {
+			t.Fatalf("RegisterGCPPropagator() error = %v", err)
+		}
+	}
+
+	if _, err := autoprop.TextMapPropagator(GCPPropagatorName); err != nil {
+		t.Errorf("autoprop.TextMapPropagator(%q) error = %v", GCPPropagatorName, err)
+	}
+}
@@ -1,7 +1,12 @@
 package otelcfg
 
 import (
+	"context"
+	"errors"
+	"sync"
+
 	"github.com/joaopenteado/runcfg"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
@@ -68,3 +73,62 @@ func NewJobResource(metadata *runcfg.Metadata, job *runcfg.Job, opts ...Resource
 		attrs...,
 	)
 }
+
+// detected caches the resource returned by detectResource, so that it is only
+// detected once when the providers are set up separately.
+var detected struct {
+	mu  sync.Mutex
+	res *resource.Resource
+}
+
+// detectResource returns the resource of the Cloud Run service or job the
+// process is running in, as detected by CloudRunDetector, merged with the SDK
+// default resource and the environment as described in mergeResources. It
+// returns nil if the process is not running on Cloud Run. Partial resources
+// are reported to the global OpenTelemetry error handler and still returned,
+// but only complete resources are reused by later calls.
+func detectResource(ctx context.Context) (*resource.Resource, error) {
+	detected.mu.Lock()
+	defer detected.mu.Unlock()
+
+	if detected.res != nil {
+		return detected.res, nil
+	}
+
+	res, err := CloudRunDetector{}.Detect(ctx)
+	if err != nil {
+		if !errors.Is(err, resource.ErrPartialResource) {
+			return nil, err
+		}
+		otel.Handle(err)
+	}
+
+	if res.Len() == 0 {
+		return nil, nil
+	}
+
+	res = mergeResources(res)
+	if err == nil {
+		detected.res = res
+	}
+	return res, nil
+}
+
+// providerOptions returns the options of a tracer, meter or logger provider
+// that do not depend on its exporter: the detected resource, unless detection
+// is disabled, followed by the options provided by the caller, so that a
+// resource set by the caller takes precedence. Call it before creating the
+// exporter, so that nothing needs to be shut down if detection fails.
+func providerOptions[O any](ctx context.Context, detect bool, withResource func(*resource.Resource) O, callerOpts []O) ([]O, error) {
+	var opts []O
+	if detect {
+		res, err := detectResource(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			opts = append(opts, withResource(res))
+		}
+	}
+	return append(opts, callerOpts...), nil
+}
@@ -0,0 +1,338 @@
+package otelcfg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/joaopenteado/runcfg/health"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Environment variables used to configure the sampler.
+const (
+	// EnvTracesSampler selects the sampler, as specified by OpenTelemetry.
+	EnvTracesSampler = "OTEL_TRACES_SAMPLER"
+
+	// EnvTracesSamplerArg is the argument of the sampler, as specified by
+	// OpenTelemetry.
+	EnvTracesSamplerArg = "OTEL_TRACES_SAMPLER_ARG"
+
+	// EnvTraceSampleRate is the ratio of traces sampled by the default
+	// sampler, between 0 and 1. Ignored if OTEL_TRACES_SAMPLER is set.
+	EnvTraceSampleRate = "TRACE_SAMPLE_RATE"
+)
+
+// DefaultExcludedPaths are the paths of requests never sampled by the default
+// sampler of SetupTracerProvider: the health probes mounted by
+// health.Registry. Pass them to WithSamplerExcludedPaths to exclude them with
+// NewSampler.
+var DefaultExcludedPaths = []string{
+	health.StartupPath,
+	health.LivenessPath,
+	health.ReadinessPath,
+}
+
+// grpcHealthService is the gRPC health checking service.
+const grpcHealthService = "grpc.health.v1.Health"
+
+// httpTargetKey is the deprecated attribute key for the request target, still
+// emitted by HTTP instrumentation using older semantic conventions.
+const httpTargetKey = attribute.Key("http.target")
+
+type samplerConfig struct {
+	excludedPaths       []string
+	disableTailSampling bool
+}
+
+type SamplerOption = option[samplerConfig]
+
+// WithSamplerExcludedPaths sets the paths of requests never sampled, such as
+// DefaultExcludedPaths. gRPC health checks are always excluded.
+func WithSamplerExcludedPaths(paths ...string) SamplerOption {
+	return optionFunc[samplerConfig](func(cfg *samplerConfig) {
+		cfg.excludedPaths = append(cfg.excludedPaths, paths...)
+	})
+}
+
+// WithErrorTailSampling sets whether spans not sampled by the base sampler are
+// recorded, so that SetupTracerProvider can still export their trace if one
+// of them ends in error. It is enabled by default. Disable it when the base
+// sampler never samples traces, such as trace.NeverSample, so that nothing is
+// recorded at all.
+func WithErrorTailSampling(enabled bool) SamplerOption {
+	return optionFunc[samplerConfig](func(cfg *samplerConfig) {
+		cfg.disableTailSampling = !enabled
+	})
+}
+
+type cloudRunSampler struct {
+	base          trace.Sampler
+	excludedPaths []string
+	recordDropped bool
+}
+
+// NewSampler returns a sampler for Cloud Run services and jobs that wraps
+// base, adding the following behavior:
+//
+//   - Requests to the paths excluded with WithSamplerExcludedPaths and gRPC
+//     health checks are dropped.
+//   - Spans not sampled by base are recorded instead of dropped, unless
+//     disabled with WithErrorTailSampling, so that SetupTracerProvider can
+//     still export their trace if one of them ends in error.
+//
+// Use trace.ParentBased for base to honor the sampled flag of the trace
+// context set by the Cloud Run front end.
+func NewSampler(base trace.Sampler, opts ...SamplerOption) trace.Sampler {
+	var cfg samplerConfig
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	return &cloudRunSampler{
+		base:          base,
+		excludedPaths: cfg.excludedPaths,
+		recordDropped: !cfg.disableTailSampling,
+	}
+}
+
+func (s *cloudRunSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingResult {
+	if s.excluded(p.Attributes) {
+		return trace.SamplingResult{
+			Decision:   trace.Drop,
+			Tracestate: oteltrace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+
+	res := s.base.ShouldSample(p)
+	if res.Decision == trace.Drop && s.recordDropped {
+		res.Decision = trace.RecordOnly
+	}
+	return res
+}
+
+func (s *cloudRunSampler) Description() string {
+	return "CloudRunSampler{" + s.base.Description() + "}"
+}
+
+// excluded reports whether a span with the given start attributes is a health
+// check.
+func (s *cloudRunSampler) excluded(attrs []attribute.KeyValue) bool {
+	for _, kv := range attrs {
+		switch kv.Key {
+		case semconv.URLPathKey, httpTargetKey:
+			// http.target may include the query string.
+			path, _, _ := strings.Cut(kv.Value.AsString(), "?")
+			if slices.Contains(s.excludedPaths, path) {
+				return true
+			}
+		case semconv.RPCServiceKey:
+			if kv.Value.AsString() == grpcHealthService {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newSampler returns the sampler configured by the environment, falling back
+// to a parent-based sampler with the given ratio. Any sampler is wrapped by
+// NewSampler to exclude DefaultExcludedPaths, with tail sampling disabled if
+// it never samples new traces.
+func newSampler(ratio float64) trace.Sampler {
+	if v, ok := os.LookupEnv(EnvTraceSampleRate); ok {
+		if r, err := strconv.ParseFloat(v, 64); err == nil {
+			ratio = r
+		} else {
+			otel.Handle(fmt.Errorf("invalid %s: %w", EnvTraceSampleRate, err))
+		}
+	}
+
+	base, samplesNothing := trace.ParentBased(trace.TraceIDRatioBased(ratio)), ratio <= 0
+	if name, ok := os.LookupEnv(EnvTracesSampler); ok {
+		if s, never, err := samplerFromEnv(name, os.Getenv(EnvTracesSamplerArg)); err == nil {
+			base, samplesNothing = s, never
+		} else {
+			otel.Handle(err)
+		}
+	}
+
+	return NewSampler(base,
+		WithSamplerExcludedPaths(DefaultExcludedPaths...),
+		WithErrorTailSampling(!samplesNothing))
+}
+
+// samplerFromEnv returns the sampler named by OTEL_TRACES_SAMPLER, supporting
+// the samplers built into the SDK, and whether it never samples new traces.
+func samplerFromEnv(name, arg string) (trace.Sampler, bool, error) {
+	ratio := 1.0
+	if arg != "" {
+		r, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid %s: %w", EnvTracesSamplerArg, err)
+		}
+		ratio = r
+	}
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "always_on":
+		return trace.AlwaysSample(), false, nil
+	case "always_off":
+		return trace.NeverSample(), true, nil
+	case "traceidratio":
+		return trace.TraceIDRatioBased(ratio), ratio <= 0, nil
+	case "parentbased_always_on":
+		return trace.ParentBased(trace.AlwaysSample()), false, nil
+	case "parentbased_always_off":
+		return trace.ParentBased(trace.NeverSample()), true, nil
+	case "parentbased_traceidratio":
+		return trace.ParentBased(trace.TraceIDRatioBased(ratio)), ratio <= 0, nil
+	default:
+		return nil, false, fmt.Errorf("unsupported %s: %q", EnvTracesSampler, name)
+	}
+}
+
+// Limits of the spans buffered by errorSpanProcessor, bounding its memory use.
+const (
+	maxBufferedTraces = 1000
+	maxBufferedSpans  = 1000
+)
+
+// errorSpanProcessor is a span processor that forwards sampled spans to the
+// wrapped processor, making a tail sampling decision for spans recorded but
+// not sampled: they are buffered per trace until the local root span ends,
+// and the whole local trace is forwarded if any of its spans ended in error.
+//
+// Once maxBufferedTraces are buffered, the oldest trace is evicted to make
+// room for a new one, so that traces whose local root span never ends do not
+// disable tail sampling. Spans of evicted traces, spans ending after their
+// local root span and spans over maxBufferedSpans are forwarded alone if they
+// ended in error, as fragments of their trace.
+type errorSpanProcessor struct {
+	trace.SpanProcessor
+
+	mu     sync.Mutex
+	traces map[oteltrace.TraceID]*bufferedTrace
+	seq    uint64
+}
+
+// bufferedTrace holds the ended spans of a local trace not sampled.
+type bufferedTrace struct {
+	spans  []trace.ReadOnlySpan
+	failed bool
+
+	// seq orders traces by the start of their local root span.
+	seq uint64
+}
+
+func newErrorSpanProcessor(next trace.SpanProcessor) *errorSpanProcessor {
+	return &errorSpanProcessor{
+		SpanProcessor: next,
+		traces:        make(map[oteltrace.TraceID]*bufferedTrace),
+	}
+}
+
+func (p *errorSpanProcessor) OnStart(parent context.Context, s trace.ReadWriteSpan) {
+	p.SpanProcessor.OnStart(parent, s)
+
+	if s.SpanContext().IsSampled() || !isLocalRoot(s) {
+		return
+	}
+
+	p.mu.Lock()
+	id := s.SpanContext().TraceID()
+	if _, ok := p.traces[id]; ok {
+		p.mu.Unlock()
+		return
+	}
+
+	var evicted *bufferedTrace
+	if len(p.traces) >= maxBufferedTraces {
+		evicted = p.evictOldest()
+	}
+	p.seq++
+	p.traces[id] = &bufferedTrace{seq: p.seq}
+	p.mu.Unlock()
+
+	if evicted != nil && evicted.failed {
+		for _, span := range evicted.spans {
+			p.SpanProcessor.OnEnd(sampledSpan{span})
+		}
+	}
+}
+
+// evictOldest removes and returns the trace whose local root span started
+// first. It must be called with p.mu held.
+func (p *errorSpanProcessor) evictOldest() *bufferedTrace {
+	var oldestID oteltrace.TraceID
+	var oldest *bufferedTrace
+	for id, t := range p.traces {
+		if oldest == nil || t.seq < oldest.seq {
+			oldestID, oldest = id, t
+		}
+	}
+	delete(p.traces, oldestID)
+	return oldest
+}
+
+func (p *errorSpanProcessor) OnEnd(s trace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.SpanProcessor.OnEnd(s)
+		return
+	}
+
+	failed := s.Status().Code == codes.Error
+	id := s.SpanContext().TraceID()
+
+	p.mu.Lock()
+	t, ok := p.traces[id]
+	if !ok || (len(t.spans) >= maxBufferedSpans && !isLocalRoot(s)) {
+		p.mu.Unlock()
+		if failed {
+			p.SpanProcessor.OnEnd(sampledSpan{s})
+		}
+		return
+	}
+
+	t.spans = append(t.spans, s)
+	t.failed = t.failed || failed
+	if !isLocalRoot(s) {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.traces, id)
+	p.mu.Unlock()
+
+	if t.failed {
+		for _, span := range t.spans {
+			p.SpanProcessor.OnEnd(sampledSpan{span})
+		}
+	}
+}
+
+// isLocalRoot reports whether s is the first span of its trace in this
+// process.
+func isLocalRoot(s trace.ReadOnlySpan) bool {
+	parent := s.Parent()
+	return !parent.IsValid() || parent.IsRemote()
+}
+
+// sampledSpan is a span whose span context is marked as sampled, so that it
+// is exported by the batch span processor.
+type sampledSpan struct {
+	trace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() oteltrace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
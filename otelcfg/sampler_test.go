@@ -0,0 +1,192 @@
+package otelcfg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+)
+
+func TestSampler(t *testing.T) {
+	tests := []struct {
+		name    string
+		sampler func(t *testing.T) trace.Sampler
+		attrs   []attribute.KeyValue
+		want    trace.SamplingDecision
+	}{
+		{
+			name: "sampled",
+			sampler: func(t *testing.T) trace.Sampler {
+				return NewSampler(trace.AlwaysSample())
+			},
+			want: trace.RecordAndSample,
+		},
+		{
+			name: "not sampled is recorded",
+			sampler: func(t *testing.T) trace.Sampler {
+				return NewSampler(trace.NeverSample())
+			},
+			want: trace.RecordOnly,
+		},
+		{
+			name: "tail sampling disabled",
+			sampler: func(t *testing.T) trace.Sampler {
+				return NewSampler(trace.NeverSample(), WithErrorTailSampling(false))
+			},
+			want: trace.Drop,
+		},
+		{
+			name: "excluded path",
+			sampler: func(t *testing.T) trace.Sampler {
+				return NewSampler(trace.AlwaysSample(), WithSamplerExcludedPaths("/livez"))
+			},
+			attrs: []attribute.KeyValue{semconv.URLPath("/livez")},
+			want:  trace.Drop,
+		},
+		{
+			name: "grpc health check",
+			sampler: func(t *testing.T) trace.Sampler {
+				return NewSampler(trace.AlwaysSample())
+			},
+			attrs: []attribute.KeyValue{semconv.RPCService(grpcHealthService)},
+			want:  trace.Drop,
+		},
+		{
+			name: "default",
+			sampler: func(t *testing.T) trace.Sampler {
+				return newSampler(1)
+			},
+			want: trace.RecordAndSample,
+		},
+		{
+			name: "default with ratio 0",
+			sampler: func(t *testing.T) trace.Sampler {
+				return newSampler(0)
+			},
+			want: trace.Drop,
+		},
+		{
+			name: "env always_off",
+			sampler: func(t *testing.T) trace.Sampler {
+				t.Setenv(EnvTracesSampler, "parentbased_always_off")
+				return newSampler(1)
+			},
+			want: trace.Drop,
+		},
+		{
+			name: "env ratio 0",
+			sampler: func(t *testing.T) trace.Sampler {
+				t.Setenv(EnvTracesSampler, "traceidratio")
+				t.Setenv(EnvTracesSamplerArg, "0")
+				return newSampler(1)
+			},
+			want: trace.Drop,
+		},
+		{
+			name: "env sample rate",
+			sampler: func(t *testing.T) trace.Sampler {
+				t.Setenv(EnvTraceSampleRate, "0")
+				return newSampler(1)
+			},
+			want: trace.Drop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.sampler(t).ShouldSample(trace.SamplingParameters{
+				ParentContext: context.Background(),
+				TraceID:       [16]byte{1},
+				Name:          "span",
+				Attributes:    tt.attrs,
+			})
+			if res.Decision != tt.want {
+				t.Errorf("ShouldSample() = %v, want %v", res.Decision, tt.want)
+			}
+		})
+	}
+}
+
+// newTestTracerProvider returns a tracer provider recording every span without
+// sampling it, and exporting spans forwarded by errorSpanProcessor to exp.
+func newTestTracerProvider(exp trace.SpanExporter) *trace.TracerProvider {
+	return trace.NewTracerProvider(
+		trace.WithSampler(NewSampler(trace.NeverSample())),
+		trace.WithSpanProcessor(newErrorSpanProcessor(trace.NewSimpleSpanProcessor(exp))),
+	)
+}
+
+func TestErrorSpanProcessor(t *testing.T) {
+	tests := []struct {
+		name   string
+		failed bool
+		want   int
+	}{
+		{
+			name: "success",
+			want: 0,
+		},
+		{
+			name:   "error",
+			failed: true,
+			want:   3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := tracetest.NewInMemoryExporter()
+			tracer := newTestTracerProvider(exp).Tracer("test")
+
+			ctx, root := tracer.Start(context.Background(), "root")
+			ctx, child := tracer.Start(ctx, "child")
+			_, grandchild := tracer.Start(ctx, "grandchild")
+			if tt.failed {
+				grandchild.SetStatus(codes.Error, "failed")
+			}
+			grandchild.End()
+			child.End()
+
+			if n := len(exp.GetSpans()); n != 0 {
+				t.Errorf("exported %d spans before the root span ended, want 0", n)
+			}
+
+			root.End()
+			if n := len(exp.GetSpans()); n != tt.want {
+				t.Errorf("exported %d spans, want %d", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorSpanProcessorEviction(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tracer := newTestTracerProvider(exp).Tracer("test")
+
+	// Local root spans that never end, such as leaked ones, fill the buffer.
+	for range maxBufferedTraces {
+		tracer.Start(context.Background(), "leaked")
+	}
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.RecordError(errors.New("failed"))
+	child.SetStatus(codes.Error, "failed")
+	child.End()
+	root.End()
+
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want the 2 spans of the failed trace", len(spans))
+	}
+	for _, s := range spans {
+		if s.SpanContext.TraceID() != root.SpanContext().TraceID() {
+			t.Errorf("exported span %q of another trace", s.Name)
+		}
+	}
+}
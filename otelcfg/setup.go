@@ -0,0 +1,306 @@
+package otelcfg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joaopenteado/runcfg"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Runtime identifies the kind of Cloud Run workload being instrumented.
+type Runtime uint8
+
+const (
+	// RuntimeService represents a Cloud Run service.
+	RuntimeService Runtime = iota
+
+	// RuntimeJob represents a Cloud Run job.
+	RuntimeJob
+)
+
+// DefaultJobFlushTimeout is the default time limit for flushing telemetry when
+// shutting down a Cloud Run job.
+const DefaultJobFlushTimeout = 10 * time.Second
+
+type setupConfig struct {
+	service      *runcfg.Service
+	job          *runcfg.Job
+	metadata     *runcfg.Metadata
+	resourceOpts []ResourceOption
+	tracerOpts   []TracerProviderOption
+	meterOpts    []MeterProviderOption
+	loggerOpts   []LoggerProviderOption
+	flushTimeout time.Duration
+}
+
+type SetupOption = option[setupConfig]
+
+// WithService sets the service configuration used to build the resource. By
+// default, it is loaded with runcfg.LoadService.
+func WithService(service *runcfg.Service) SetupOption {
+	return optionFunc[setupConfig](func(cfg *setupConfig) {
+		cfg.service = service
+	})
+}
+
+// WithJob sets the job configuration used to build the resource. By default,
+// it is loaded with runcfg.LoadJob.
+func WithJob(job *runcfg.Job) SetupOption {
+	return optionFunc[setupConfig](func(cfg *setupConfig) {
+		cfg.job = job
+	})
+}
+
+// WithMetadata sets the metadata used to build the resource. By default, it is
+// loaded by CloudRunDetector, falling back to the environment if the metadata
+// server cannot be reached.
+func WithMetadata(metadata *runcfg.Metadata) SetupOption {
+	return optionFunc[setupConfig](func(cfg *setupConfig) {
+		cfg.metadata = metadata
+	})
+}
+
+// WithResourceOptions sets the options used to build the resource.
+func WithResourceOptions(opts ...ResourceOption) SetupOption {
+	return optionFunc[setupConfig](func(cfg *setupConfig) {
+		cfg.resourceOpts = append(cfg.resourceOpts, opts...)
+	})
+}
+
+// WithTracing sets the options passed to SetupTracerProvider.
+func WithTracing(opts ...TracerProviderOption) SetupOption {
+	return optionFunc[setupConfig](func(cfg *setupConfig) {
+		cfg.tracerOpts = append(cfg.tracerOpts, opts...)
+	})
+}
+
+// WithMetrics sets the options passed to SetupMeterProvider.
+func WithMetrics(opts ...MeterProviderOption) SetupOption {
+	return optionFunc[setupConfig](func(cfg *setupConfig) {
+		cfg.meterOpts = append(cfg.meterOpts, opts...)
+	})
+}
+
+// WithLogging sets the options passed to SetupLoggerProvider.
+func WithLogging(opts ...LoggerProviderOption) SetupOption {
+	return optionFunc[setupConfig](func(cfg *setupConfig) {
+		cfg.loggerOpts = append(cfg.loggerOpts, opts...)
+	})
+}
+
+// WithFlushTimeout sets the time limit for flushing buffered telemetry in
+// [Telemetry.Shutdown]. Defaults to DefaultJobFlushTimeout for jobs, while
+// services are only bound by the context passed to Shutdown. Zero or a
+// negative value disables the limit.
+func WithFlushTimeout(timeout time.Duration) SetupOption {
+	return optionFunc[setupConfig](func(cfg *setupConfig) {
+		cfg.flushTimeout = timeout
+	})
+}
+
+// Telemetry holds the providers configured by Setup.
+type Telemetry struct {
+	// Resource describes the Cloud Run service or job emitting telemetry.
+	Resource *resource.Resource
+
+	// Metadata used to build the resource. Use Metadata.ProjectID to
+	// configure zerologcfg.Hook.
+	Metadata *runcfg.Metadata
+
+	// TracerProvider is the global tracer provider.
+	TracerProvider *trace.TracerProvider
+
+	// MeterProvider is the global meter provider.
+	MeterProvider *metric.MeterProvider
+
+	// LoggerProvider is the global logger provider. Use NewZerologWriter to
+	// bridge zerolog loggers to it.
+	LoggerProvider *sdklog.LoggerProvider
+
+	flushTimeout time.Duration
+}
+
+// Setup configures tracing, metrics, logs and context propagation for a Cloud
+// Run service or job in a single call. The resource is built with
+// CloudRunDetector depending on runtime and attached to every provider.
+// Configuration not provided through options is loaded from the environment
+// and the metadata server. If the metadata server cannot be reached, the
+// resource only holds the attributes known from the environment, and the
+// error is reported to the global OpenTelemetry error handler.
+//
+// Call [Telemetry.Shutdown] before the program exits to flush all buffered
+// telemetry. Cloud Run job tasks often finish before the first periodic
+// export, so for RuntimeJob the final flush is what exports most telemetry,
+// and it is bound by DefaultJobFlushTimeout unless changed with
+// WithFlushTimeout.
+func Setup(ctx context.Context, runtime Runtime, opts ...SetupOption) (*Telemetry, error) {
+	var cfg setupConfig
+	if runtime == RuntimeJob {
+		cfg.flushTimeout = DefaultJobFlushTimeout
+	}
+
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	detector := CloudRunDetector{Metadata: cfg.metadata}
+	switch runtime {
+	case RuntimeService:
+		if cfg.service == nil {
+			svc, err := runcfg.LoadService()
+			if err != nil {
+				return nil, err
+			}
+			cfg.service = svc
+		}
+		detector.Service = cfg.service
+	case RuntimeJob:
+		if cfg.job == nil {
+			job, err := runcfg.LoadJob()
+			if err != nil {
+				return nil, err
+			}
+			cfg.job = job
+		}
+		detector.Job = cfg.job
+	default:
+		return nil, fmt.Errorf("unknown runtime: %d", runtime)
+	}
+
+	// A partial resource, such as when the metadata server cannot be reached,
+	// is better than no telemetry at all.
+	res, md, err := detector.detect(ctx)
+	if err != nil {
+		if !errors.Is(err, resource.ErrPartialResource) {
+			return nil, err
+		}
+		otel.Handle(err)
+	}
+
+	if len(cfg.resourceOpts) > 0 {
+		var rcfg resourceConfig
+		for _, opt := range cfg.resourceOpts {
+			opt.apply(&rcfg)
+		}
+		// Cloud Run attributes take precedence, as in NewServiceResource.
+		res, _ = resource.Merge(resource.NewSchemaless(rcfg.attrs...), res)
+	}
+	res = mergeResources(res)
+
+	t := &Telemetry{
+		Resource:     res,
+		Metadata:     md,
+		flushTimeout: cfg.flushTimeout,
+	}
+
+	// Options provided by the caller are applied last, so they take
+	// precedence over the resource set here. The resource is already known,
+	// so there is no need to detect it again.
+	tracerOpts := append([]TracerProviderOption{
+		WithTracerResourceDetection(false),
+		WithTracerProviderOptions(trace.WithResource(res)),
+	}, cfg.tracerOpts...)
+
+	tp, err := SetupTracerProvider(ctx, tracerOpts...)
+	if err != nil {
+		return nil, err
+	}
+	t.TracerProvider = tp
+
+	meterOpts := append([]MeterProviderOption{
+		WithMeterResourceDetection(false),
+		WithMetricOptions(metric.WithResource(res)),
+	}, cfg.meterOpts...)
+
+	mp, err := SetupMeterProvider(ctx, meterOpts...)
+	if err != nil {
+		return nil, errors.Join(err, t.Shutdown(ctx))
+	}
+	t.MeterProvider = mp
+
+	loggerOpts := append([]LoggerProviderOption{
+		WithLoggerResourceDetection(false),
+		WithLoggerProviderOptions(sdklog.WithResource(res)),
+	}, cfg.loggerOpts...)
+
+	lp, err := SetupLoggerProvider(ctx, loggerOpts...)
+	if err != nil {
+		return nil, errors.Join(err, t.Shutdown(ctx))
+	}
+	t.LoggerProvider = lp
+
+	return t, nil
+}
+
+// Shutdown flushes and shuts down every provider, in order: traces first, then
+// metrics and finally logs, so that logs written while shutting down the other
+// providers are still exported. Shutting down the meter provider collects and
+// exports metrics one last time. All providers are shut down even if one
+// fails, and the errors are joined together.
+//
+// The outcome of the flush is logged with the logger returned by zerolog.Ctx.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	if t.flushTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.flushTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	var errs []error
+
+	if t.TracerProvider != nil {
+		if err := t.TracerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("tracer provider: %w", err))
+		}
+	}
+
+	if t.MeterProvider != nil {
+		if err := t.MeterProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("meter provider: %w", err))
+		}
+	}
+
+	if t.LoggerProvider != nil {
+		if err := t.LoggerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("logger provider: %w", err))
+		}
+	}
+
+	logger := zerolog.Ctx(ctx)
+	if err := errors.Join(errs...); err != nil {
+		logger.Error().Err(err).Dur("elapsed", time.Since(start)).Msg("telemetry flush failed")
+		return err
+	}
+
+	logger.Info().Dur("elapsed", time.Since(start)).Msg("telemetry flushed")
+	return nil
+}
+
+// mergeResources merges res with the SDK default resource and the resource
+// described by the OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME environment
+// variables, in order of increasing precedence: SDK defaults, res and then
+// the environment.
+func mergeResources(res *resource.Resource) *resource.Resource {
+	merged := resource.Default()
+	for _, r := range []*resource.Resource{res, resource.Environment()} {
+		// The SDK defaults may use a different semantic conventions version.
+		// On conflicting schema URLs, Merge still returns the merged
+		// attributes without a schema URL, which is preferable to dropping
+		// them.
+		m, err := resource.Merge(merged, r)
+		if err != nil && !errors.Is(err, resource.ErrSchemaURLConflict) {
+			continue
+		}
+		merged = m
+	}
+	return merged
+}
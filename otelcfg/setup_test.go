@@ -0,0 +1,78 @@
+package otelcfg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+)
+
+type errorHandlerFunc func(error)
+
+func (f errorHandlerFunc) Handle(err error) { f(err) }
+
+func TestSetupMetadataServerUnreachable(t *testing.T) {
+	clearCloudRunEnv(t)
+	unreachableMetadataServer(t)
+	t.Setenv("K_SERVICE", "api")
+	t.Setenv("GOOGLE_CLOUD_REGION", "europe-west1")
+
+	var (
+		mu      sync.Mutex
+		handled []error
+	)
+	prev := otel.GetErrorHandler()
+	otel.SetErrorHandler(errorHandlerFunc(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, err)
+	}))
+	t.Cleanup(func() { otel.SetErrorHandler(prev) })
+
+	ctx := context.Background()
+	tel, err := Setup(ctx, RuntimeService,
+		WithTracing(WithSpanExporter(tracetest.NewInMemoryExporter())),
+		WithMetrics(WithReader(metric.NewManualReader())),
+		WithLogging(WithLogExporter(CloudLoggingStdoutExporter(io.Discard))),
+	)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := tel.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown() error = %v", err)
+		}
+	})
+
+	if tel.TracerProvider == nil || tel.MeterProvider == nil || tel.LoggerProvider == nil {
+		t.Errorf("Setup() = %+v, want every provider set up", tel)
+	}
+
+	for _, want := range []attribute.KeyValue{
+		semconv.ServiceName("api"),
+		semconv.CloudRegion("europe-west1"),
+	} {
+		if got, ok := tel.Resource.Set().Value(want.Key); !ok || got != want.Value {
+			t.Errorf("%s = %v, want %v", want.Key, got.Emit(), want.Value.Emit())
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var partial bool
+	for _, err := range handled {
+		partial = partial || errors.Is(err, resource.ErrPartialResource)
+	}
+	if !partial {
+		t.Errorf("reported errors %v, want a partial resource error", handled)
+	}
+}
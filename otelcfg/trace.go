@@ -7,16 +7,27 @@ import (
 	"go.opentelemetry.io/contrib/propagators/autoprop"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/trace"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/oauth"
+)
+
+const (
+	// cloudTraceEndpointURL is the Google Cloud Telemetry endpoint for traces.
+	cloudTraceEndpointURL = "https://telemetry.googleapis.com:443/v1/traces"
+
+	// cloudTraceScope is the OAuth2 scope required to write traces.
+	cloudTraceScope = "https://www.googleapis.com/auth/trace.append"
 )
 
 type traceProviderConfig struct {
-	textMapPropagator  propagation.TextMapPropagator
-	exporter           trace.SpanExporter
-	tracerProviderOpts []trace.TracerProviderOption
+	textMapPropagator        propagation.TextMapPropagator
+	exporter                 trace.SpanExporter
+	exporterOpts             []OTLPExporterOption
+	sampler                  trace.Sampler
+	sampleRatio              float64
+	tracerProviderOpts       []trace.TracerProviderOption
+	disableResourceDetection bool
 }
 
 type TracerProviderOption = option[traceProviderConfig]
@@ -29,6 +40,14 @@ func WithTextMapPropagator(propagator propagation.TextMapPropagator) TracerProvi
 	})
 }
 
+// WithGCPPropagator sets the text map propagator for the tracer provider to
+// the one returned by NewGCPPropagator, which also reads the
+// X-Cloud-Trace-Context header. This is equivalent to setting
+// OTEL_PROPAGATORS=gcp.
+func WithGCPPropagator() TracerProviderOption {
+	return WithTextMapPropagator(NewGCPPropagator())
+}
+
 // WithSpanExporter sets the span exporter for the tracer provider.
 // By default, the exporter will be configured by autoexport, falling back to
 // the CloudTraceOLTPExporter if no environment-specific exporter is detected.
@@ -38,6 +57,50 @@ func WithSpanExporter(exporter trace.SpanExporter) TracerProviderOption {
 	})
 }
 
+// WithCloudTraceExporterOptions sets the options passed to
+// NewCloudTraceOTLPExporter when no span exporter is set and autoexport falls
+// back to it.
+func WithCloudTraceExporterOptions(opts ...OTLPExporterOption) TracerProviderOption {
+	return optionFunc[traceProviderConfig](func(cfg *traceProviderConfig) {
+		cfg.exporterOpts = append(cfg.exporterOpts, opts...)
+	})
+}
+
+// WithSampler sets the sampler for the tracer provider, used as is. By
+// default, the sampler is configured as described in WithSampleRatio.
+func WithSampler(sampler trace.Sampler) TracerProviderOption {
+	return optionFunc[traceProviderConfig](func(cfg *traceProviderConfig) {
+		cfg.sampler = sampler
+	})
+}
+
+// WithSampleRatio sets the ratio of traces sampled by the default sampler, a
+// parent-based ratio sampler which honors the sampled flag set by the Cloud
+// Run front end. Defaults to 1, sampling every trace not sampled out by its
+// parent. The ratio is overridden by the TRACE_SAMPLE_RATE environment
+// variable, and the whole sampler by OTEL_TRACES_SAMPLER and
+// OTEL_TRACES_SAMPLER_ARG. In every case, the sampler is wrapped by NewSampler
+// to exclude DefaultExcludedPaths, and traces with a span ending in error are
+// exported even if not sampled, unless the sampler never samples traces.
+func WithSampleRatio(ratio float64) TracerProviderOption {
+	return optionFunc[traceProviderConfig](func(cfg *traceProviderConfig) {
+		cfg.sampleRatio = ratio
+	})
+}
+
+// WithTracerResourceDetection sets whether the tracer provider resource is
+// detected automatically. When enabled, which is the default, the resource of
+// the Cloud Run service or job is detected with CloudRunDetector and merged
+// with the SDK default resource and the OTEL_RESOURCE_ATTRIBUTES and
+// OTEL_SERVICE_NAME environment variables, with the environment taking
+// precedence. Nothing is detected outside of Cloud Run.
+// A resource set with WithTracerProviderOptions always takes precedence.
+func WithTracerResourceDetection(enabled bool) TracerProviderOption {
+	return optionFunc[traceProviderConfig](func(cfg *traceProviderConfig) {
+		cfg.disableResourceDetection = !enabled
+	})
+}
+
 // WithTracerProviderOptions sets additional options for the tracer provider.
 func WithTracerProviderOptions(opts ...trace.TracerProviderOption) TracerProviderOption {
 	return optionFunc[traceProviderConfig](func(cfg *traceProviderConfig) {
@@ -46,19 +109,30 @@ func WithTracerProviderOptions(opts ...trace.TracerProviderOption) TracerProvide
 }
 
 func SetupTracerProvider(ctx context.Context, opts ...TracerProviderOption) (*trace.TracerProvider, error) {
-	var cfg traceProviderConfig
+	cfg := traceProviderConfig{
+		sampleRatio: 1,
+	}
+
 	for _, opt := range opts {
 		opt.apply(&cfg)
 	}
 
+	tpOpts, err := providerOptions(ctx, !cfg.disableResourceDetection, trace.WithResource, cfg.tracerProviderOpts)
+	if err != nil {
+		return nil, err
+	}
+
 	if cfg.textMapPropagator == nil {
 		// Configure Context Propagation to use the default W3C traceparent
 		// format. This can be overriden by the OTEL_PROPAGATORS environment
 		// variable. By default, the default propagators is a composite of the
-		// tracecontext and baggage propagators.
+		// tracecontext and baggage propagators. Setting OTEL_PROPAGATORS=gcp
+		// selects the GCP propagator, which also reads X-Cloud-Trace-Context.
+		if err := RegisterGCPPropagator(); err != nil {
+			otel.Handle(err)
+		}
 		cfg.textMapPropagator = autoprop.NewTextMapPropagator()
 	}
-	otel.SetTextMapPropagator(cfg.textMapPropagator)
 
 	if cfg.exporter == nil {
 		// Create a new span exporter using autoexport, which will automatically
@@ -66,7 +140,11 @@ func SetupTracerProvider(ctx context.Context, opts ...TracerProviderOption) (*tr
 		// If no environment-specific exporter is detected, it will fall back to
 		// using the CloudTraceOLTPExporter which exports spans to Google Cloud
 		// Trace via the OpenTelemetry Protocol (OTLP).
-		exporter, err := autoexport.NewSpanExporter(ctx, autoexport.WithFallbackSpanExporter(CloudTraceOLTPExporter))
+		exporter, err := autoexport.NewSpanExporter(ctx, autoexport.WithFallbackSpanExporter(
+			func(ctx context.Context) (trace.SpanExporter, error) {
+				return NewCloudTraceOTLPExporter(ctx, cfg.exporterOpts...)
+			},
+		))
 		if err != nil {
 			return nil, err
 		}
@@ -75,34 +153,75 @@ func SetupTracerProvider(ctx context.Context, opts ...TracerProviderOption) (*tr
 		// exporter. No need to explicitly call the shutdown function.
 	}
 
-	tpOpts := make([]trace.TracerProviderOption, 1, 1+len(cfg.tracerProviderOpts))
-	tpOpts[0] = trace.WithBatcher(cfg.exporter)
-	tpOpts = append(tpOpts, cfg.tracerProviderOpts...)
+	if cfg.sampler == nil {
+		cfg.sampler = newSampler(cfg.sampleRatio)
+	}
 
-	tp := trace.NewTracerProvider(tpOpts...)
+	// Spans recorded but not sampled by the sampler are only exported, along
+	// with the rest of their local trace, if one of them ends in error.
+	tp := trace.NewTracerProvider(append([]trace.TracerProviderOption{
+		trace.WithSpanProcessor(newErrorSpanProcessor(trace.NewBatchSpanProcessor(cfg.exporter))),
+		trace.WithSampler(cfg.sampler),
+	}, tpOpts...)...)
 
-	// Set the tracer provider as the global tracer provider.
+	// Set the propagator and the tracer provider globally only once setup can
+	// no longer fail.
+	otel.SetTextMapPropagator(cfg.textMapPropagator)
 	otel.SetTracerProvider(tp)
 
 	return tp, nil
 }
 
 // CloudTraceOLTPExporter creates a new span exporter for the Cloud Trace
-// Telemetry OpenTelemetry Protocol (OTLP) endpoint.
+// Telemetry OpenTelemetry Protocol (OTLP) endpoint, exporting spans over gRPC
+// using Application Default Credentials. Use NewCloudTraceOTLPExporter to
+// configure it.
 // https://cloud.google.com/trace/docs/migrate-to-otlp-endpoints#telemetry_replace-go
 func CloudTraceOLTPExporter(ctx context.Context) (trace.SpanExporter, error) {
-	creds, err := oauth.NewApplicationDefault(ctx, "https://www.googleapis.com/auth/trace.append")
+	return NewCloudTraceOTLPExporter(ctx)
+}
+
+// NewCloudTraceOTLPExporter creates a new span exporter for the Cloud Trace
+// Telemetry OpenTelemetry Protocol (OTLP) endpoint. By default, spans are
+// exported over gRPC using Application Default Credentials; use opts to
+// change the endpoint, protocol, credentials, headers or compression.
+func NewCloudTraceOTLPExporter(ctx context.Context, opts ...OTLPExporterOption) (trace.SpanExporter, error) {
+	cfg, err := newOTLPExporterConfig(ctx, cloudTraceEndpointURL, cloudTraceScope, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	exporter, err := otlptracegrpc.New(ctx,
-		otlptracegrpc.WithEndpointURL("https://telemetry.googleapis.com:443/v1/traces"),
-		otlptracegrpc.WithDialOption(grpc.WithPerRPCCredentials(creds)),
-	)
-	if err != nil {
-		return nil, err
+	if cfg.protocol == ProtocolHTTPProtobuf {
+		httpOpts := []otlptracehttp.Option{
+			otlptracehttp.WithEndpointURL(cfg.endpointURL),
+			otlptracehttp.WithHTTPClient(cfg.httpClient()),
+		}
+		if cfg.insecure {
+			httpOpts = append(httpOpts, otlptracehttp.WithInsecure())
+		}
+		if len(cfg.headers) > 0 {
+			httpOpts = append(httpOpts, otlptracehttp.WithHeaders(cfg.headers))
+		}
+		if cfg.gzip {
+			httpOpts = append(httpOpts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+		return otlptracehttp.New(ctx, httpOpts...)
 	}
 
-	return exporter, nil
+	grpcOpts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpointURL(cfg.endpointURL),
+	}
+	for _, opt := range cfg.grpcDialOptions() {
+		grpcOpts = append(grpcOpts, otlptracegrpc.WithDialOption(opt))
+	}
+	if cfg.insecure {
+		grpcOpts = append(grpcOpts, otlptracegrpc.WithInsecure())
+	}
+	if len(cfg.headers) > 0 {
+		grpcOpts = append(grpcOpts, otlptracegrpc.WithHeaders(cfg.headers))
+	}
+	if cfg.gzip {
+		grpcOpts = append(grpcOpts, otlptracegrpc.WithCompressor("gzip"))
+	}
+	return otlptracegrpc.New(ctx, grpcOpts...)
 }
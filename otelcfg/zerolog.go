@@ -0,0 +1,165 @@
+package otelcfg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// zerologBridgeName is the instrumentation scope name of the zerolog bridge.
+const zerologBridgeName = "github.com/joaopenteado/runcfg/otelcfg"
+
+type zerologWriter struct {
+	logger log.Logger
+}
+
+// NewZerologWriter returns an io.Writer that bridges zerolog to OpenTelemetry.
+// Each JSON log entry written by zerolog is converted into a log record and
+// emitted through provider, such as the one returned by SetupLoggerProvider,
+// so it carries the resource of the provider.
+//
+// Entries are expected in the format produced by loggers configured with
+// zerologcfg.Hook. The Cloud Logging severity and source location fields are
+// converted to their OpenTelemetry equivalents, and the trace and span IDs are
+// used as the trace context of the record. Any other field becomes a record
+// attribute.
+//
+// To export logs both to stdout and through the provider, combine the writer
+// with zerolog.MultiLevelWriter. Avoid doing so when the provider itself
+// exports to stdout, as every entry would be written twice.
+func NewZerologWriter(provider log.LoggerProvider) io.Writer {
+	return &zerologWriter{
+		logger: provider.Logger(zerologBridgeName),
+	}
+}
+
+func (w *zerologWriter) Write(p []byte) (int, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return 0, err
+	}
+
+	var r log.Record
+	r.SetObservedTimestamp(time.Now())
+
+	var sc trace.SpanContextConfig
+	for key, val := range fields {
+		switch key {
+		case cloudLoggingSeverityKey, "level":
+			text, _ := val.(string)
+			r.SetSeverity(zerologSeverity(text))
+			r.SetSeverityText(text)
+		case cloudLoggingMessageKey:
+			r.SetBody(anyLogValue(val))
+		case cloudLoggingTimeKey:
+			if text, ok := val.(string); ok {
+				if ts, err := time.Parse(time.RFC3339Nano, text); err == nil {
+					r.SetTimestamp(ts)
+				}
+			}
+		case cloudLoggingSourceLocationKey:
+			loc, _ := val.(map[string]any)
+			if file, ok := loc["file"].(string); ok && file != "" {
+				r.AddAttributes(log.String(string(semconv.CodeFilePathKey), file))
+			}
+			if line, ok := loc["line"].(string); ok {
+				if n, err := strconv.ParseInt(line, 10, 64); err == nil {
+					r.AddAttributes(log.Int64(string(semconv.CodeLineNumberKey), n))
+				}
+			}
+			if function, ok := loc["function"].(string); ok && function != "" {
+				r.AddAttributes(log.String(string(semconv.CodeFunctionNameKey), function))
+			}
+		case cloudLoggingTraceKey:
+			// Trace is in the format projects/{project}/traces/{trace}
+			text, _ := val.(string)
+			sc.TraceID, _ = trace.TraceIDFromHex(text[strings.LastIndexByte(text, '/')+1:])
+		case cloudLoggingSpanIDKey:
+			text, _ := val.(string)
+			sc.SpanID, _ = trace.SpanIDFromHex(text)
+		case cloudLoggingTraceSampledKey:
+			if sampled, _ := val.(bool); sampled {
+				sc.TraceFlags = trace.FlagsSampled
+			}
+		default:
+			r.AddAttributes(log.KeyValue{Key: key, Value: anyLogValue(val)})
+		}
+	}
+
+	ctx := context.Background()
+	if spanCtx := trace.NewSpanContext(sc); spanCtx.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, spanCtx)
+	}
+
+	w.logger.Emit(ctx, r)
+
+	return len(p), nil
+}
+
+// zerologSeverity maps a Cloud Logging severity, as written by loggers
+// configured by zerologcfg, or a zerolog level to an OpenTelemetry severity.
+func zerologSeverity(text string) log.Severity {
+	switch strings.ToUpper(text) {
+	case "TRACE":
+		return log.SeverityTrace
+	case "DEBUG":
+		return log.SeverityDebug
+	case "INFO":
+		return log.SeverityInfo
+	case "NOTICE":
+		return log.SeverityInfo2
+	case "WARNING", "WARN":
+		return log.SeverityWarn
+	case "ERROR":
+		return log.SeverityError
+	case "CRITICAL", "FATAL":
+		return log.SeverityFatal
+	case "ALERT", "PANIC":
+		return log.SeverityFatal2
+	case "EMERGENCY":
+		return log.SeverityFatal4
+	default:
+		return log.SeverityUndefined
+	}
+}
+
+// anyLogValue converts a value decoded from JSON to a log value.
+func anyLogValue(val any) log.Value {
+	switch v := val.(type) {
+	case string:
+		return log.StringValue(v)
+	case bool:
+		return log.BoolValue(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return log.Int64Value(n)
+		}
+		f, _ := v.Float64()
+		return log.Float64Value(f)
+	case []any:
+		vals := make([]log.Value, len(v))
+		for i, elem := range v {
+			vals[i] = anyLogValue(elem)
+		}
+		return log.SliceValue(vals...)
+	case map[string]any:
+		kvs := make([]log.KeyValue, 0, len(v))
+		for key, elem := range v {
+			kvs = append(kvs, log.KeyValue{Key: key, Value: anyLogValue(elem)})
+		}
+		return log.MapValue(kvs...)
+	default:
+		return log.Value{}
+	}
+}
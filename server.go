@@ -0,0 +1,167 @@
+package runcfg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultReadHeaderTimeout is the default amount of time allowed to read
+// request headers by servers started with [Service.ListenAndServe].
+const DefaultReadHeaderTimeout = 10 * time.Second
+
+type serveConfig struct {
+	readHeaderTimeout time.Duration
+	shutdownTimeout   time.Duration
+	signals           []os.Signal
+	h2c               bool
+	serverOpts        []func(*http.Server)
+}
+
+type ServeOption func(*serveConfig)
+
+// WithReadHeaderTimeout specifies the amount of time allowed to read request
+// headers. By default, DefaultReadHeaderTimeout is used.
+func WithReadHeaderTimeout(timeout time.Duration) ServeOption {
+	return func(o *serveConfig) {
+		o.readHeaderTimeout = timeout
+	}
+}
+
+// WithServerShutdownTimeout specifies how long to wait for active connections
+// to finish once shutdown begins. By default, DefaultShutdownTimeout is used.
+// Non-positive values are ignored.
+func WithServerShutdownTimeout(timeout time.Duration) ServeOption {
+	return func(o *serveConfig) {
+		if timeout > 0 {
+			o.shutdownTimeout = timeout
+		}
+	}
+}
+
+// WithServerShutdownSignals specifies the signals that trigger a graceful
+// shutdown of the server. By default, SIGTERM and SIGINT are used.
+func WithServerShutdownSignals(signals ...os.Signal) ServeOption {
+	return func(o *serveConfig) {
+		if len(signals) > 0 {
+			o.signals = signals
+		}
+	}
+}
+
+// WithH2C enables unencrypted HTTP/2 (h2c) in addition to HTTP/1.1. This is
+// required to serve end-to-end HTTP/2 on Cloud Run, where TLS is terminated by
+// the front end and requests are forwarded to the container in plaintext.
+func WithH2C() ServeOption {
+	return func(o *serveConfig) {
+		o.h2c = true
+	}
+}
+
+// WithHTTPServer specifies a function to customize the *http.Server before it
+// starts serving. It is applied after all defaults are set, so any field can be
+// overridden. If multiple functions are provided, they are applied in order.
+func WithHTTPServer(fn func(*http.Server)) ServeOption {
+	return func(o *serveConfig) {
+		o.serverOpts = append(o.serverOpts, fn)
+	}
+}
+
+// Addr returns the TCP address the service should listen on, in the form
+// ":port".
+func (s *Service) Addr() string {
+	return ":" + strconv.FormatUint(uint64(s.Port), 10)
+}
+
+// Listener returns a TCP listener bound to the service port. It can be used to
+// start custom servers. It returns ErrInvalidPort if the port is 0.
+func (s *Service) Listener() (net.Listener, error) {
+	if s.Port == 0 {
+		return nil, fmt.Errorf("%w: port cannot be 0", ErrInvalidPort)
+	}
+
+	return net.Listen("tcp", s.Addr())
+}
+
+// ListenAndServe starts an HTTP server listening on the service port and
+// serving handler, with defaults suited for Cloud Run. It blocks until ctx is
+// canceled or one of the shutdown signals is received, then gracefully shuts
+// down the server.
+//
+// Requests are served with a base context derived from ctx that retains its
+// values but is not canceled with it, so in-flight requests can complete
+// during shutdown.
+//
+// ListenAndServe returns nil if the server was shut down gracefully, or an
+// error if the server failed to start or shutdown did not complete in time.
+func (s *Service) ListenAndServe(ctx context.Context, handler http.Handler, opts ...ServeOption) error {
+	cfg := serveConfig{
+		readHeaderTimeout: DefaultReadHeaderTimeout,
+		shutdownTimeout:   DefaultShutdownTimeout,
+		signals:           []os.Signal{syscall.SIGTERM, os.Interrupt},
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ln, err := s.Listener()
+	if err != nil {
+		return err
+	}
+
+	baseCtx := context.WithoutCancel(ctx)
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.readHeaderTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			return baseCtx
+		},
+	}
+
+	if cfg.h2c {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		srv.Protocols = &protocols
+	}
+
+	for _, fn := range cfg.serverOpts {
+		fn(srv)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, cfg.signals...)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		// The server stopped without being asked to
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(baseCtx, cfg.shutdownTimeout)
+	defer cancel()
+
+	if err := ShutdownHTTPServer(srv)(shutdownCtx); err != nil {
+		return errors.Join(ErrShutdown, err)
+	}
+
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
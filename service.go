@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+
+	"github.com/rs/zerolog"
 )
 
 // Service contains environment variables available to Cloud Run services.
@@ -31,6 +33,9 @@ type Service struct {
 	// The name of the Cloud Run configuration that created the revision.
 	// Read from `K_CONFIGURATION` environment variable.
 	Configuration string
+
+	// Whether the PORT environment variable was set on the last reload.
+	ingress bool
 }
 
 func defaultService() *Service {
@@ -74,6 +79,17 @@ func WithDefaultPortString(portStrings ...string) ServiceLoadOption {
 	}
 }
 
+// WithSidecar specifies that the service is being loaded in a sidecar
+// container. Since Cloud Run only sets the PORT environment variable for the
+// ingress container, Port is not defaulted to 8080 and remains 0 unless the
+// PORT environment variable is set. Options applied after WithSidecar may
+// still set a default port.
+func WithSidecar() ServiceLoadOption {
+	return func(o *Service) {
+		o.Port = 0
+	}
+}
+
 // WithDefaultServiceName specifies the default name to use if the K_SERVICE
 // environment variable is not set. If multiple names are provided, the first
 // non-empty name will be used.
@@ -156,7 +172,9 @@ func (s *Service) Reload() error {
 		s.Configuration = configuration
 	}
 
-	if portStr := os.Getenv("PORT"); portStr != "" {
+	portStr := os.Getenv("PORT")
+	s.ingress = portStr != ""
+	if portStr != "" {
 		port, err := strconv.ParseUint(portStr, 10, 16)
 		if err != nil {
 			return errors.Join(ErrEnvironmentProcess, ErrInvalidPort, err)
@@ -170,6 +188,16 @@ func (s *Service) Reload() error {
 	return nil
 }
 
+// IsIngress reports whether the service is running in the ingress container,
+// the one receiving requests. Cloud Run only sets the PORT environment
+// variable for the ingress container, so IsIngress returns true if PORT was
+// set when the Service was last loaded with LoadService, Reload or EnvDecode,
+// and false for a Service that was never loaded. Note that this is also false
+// when running outside of Cloud Run without PORT set.
+func (s *Service) IsIngress() bool {
+	return s.ingress
+}
+
 // EnvDecode implements the [envconfig.DecoderCtx] interface from
 // github.com/sethvargo/go-envconfig. This ensures that [envconfig.Process] will
 // return errors derived from [ErrEnvironmentProcess] and [ErrInvalidPort] if
@@ -194,3 +222,14 @@ func (s *Service) EnvDecode(ctx context.Context, val string) error {
 
 	return s.Reload()
 }
+
+// MarshalZerologObject implements [zerolog.LogObjectMarshaler], so that the
+// configuration can be logged with zerolog.Event.Object, such as at startup
+// for debugging.
+func (s *Service) MarshalZerologObject(e *zerolog.Event) {
+	e.Uint16("port", s.Port).
+		Str("name", s.Name).
+		Str("revision", s.Revision).
+		Str("configuration", s.Configuration).
+		Bool("ingress", s.ingress)
+}
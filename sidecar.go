@@ -0,0 +1,52 @@
+package runcfg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	sidecarPollInitialInterval = 50 * time.Millisecond
+	sidecarPollMaxInterval     = time.Second
+)
+
+// WaitForSidecar blocks until a sidecar container accepts TCP connections on
+// the given localhost port, such as an OpenTelemetry collector or the Cloud SQL
+// Auth Proxy. Containers in a Cloud Run instance share the network namespace,
+// so sidecars are reachable on localhost. The port is polled with an
+// increasing interval until it accepts a connection, ctx is done or the
+// timeout expires, in which case an error derived from ErrSidecarUnavailable
+// is returned.
+func WaitForSidecar(ctx context.Context, port uint16, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addr := net.JoinHostPort("localhost", strconv.FormatUint(uint64(port), 10))
+	interval := sidecarPollInitialInterval
+
+	var d net.Dialer
+	for {
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			return conn.Close()
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(
+				fmt.Errorf("%w: %s did not accept connections", ErrSidecarUnavailable, addr),
+				context.Cause(ctx),
+				err,
+			)
+		case <-timer.C:
+		}
+
+		interval = min(interval*2, sidecarPollMaxInterval)
+	}
+}
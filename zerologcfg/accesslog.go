@@ -0,0 +1,173 @@
+package zerologcfg
+
+import (
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// HTTPRequestFieldName is the field name of the structured HTTP request
+// recognized by Cloud Logging.
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+const HTTPRequestFieldName = "httpRequest"
+
+// DefaultHealthCheckPaths are the health check paths sampled out by
+// WithHealthCheckSampling when none are given, matching the probe paths of
+// runcfg/health.
+var DefaultHealthCheckPaths = []string{"/startupz", "/livez", "/readyz"}
+
+type accessLogConfig struct {
+	healthCheckPaths []string
+	healthCheckRate  float64
+}
+
+// AccessLogOption configures the AccessLog middleware.
+type AccessLogOption func(*accessLogConfig)
+
+// WithHealthCheckSampling logs successful requests to the given health check
+// paths with probability rate, between 0 and 1. A rate of 0 never logs them.
+// Failed health checks are always logged. If no paths are given,
+// DefaultHealthCheckPaths are used.
+func WithHealthCheckSampling(rate float64, paths ...string) AccessLogOption {
+	return func(cfg *accessLogConfig) {
+		if len(paths) == 0 {
+			paths = DefaultHealthCheckPaths
+		}
+		cfg.healthCheckPaths = paths
+		cfg.healthCheckRate = rate
+	}
+}
+
+// AccessLog returns a middleware that logs one entry per request with the
+// httpRequest field recognized by Cloud Logging. The severity is derived from
+// the response status code: ERROR for 5xx, WARNING for 4xx and INFO otherwise.
+//
+// The entry is logged with logger bound to the request context, so the trace
+// fields are added by Hook. Ensure that this middleware is ran after any
+// other middleware that injects tracing information to the request context.
+func AccessLog(logger zerolog.Logger, opts ...AccessLogOption) func(next http.Handler) http.Handler {
+	var cfg accessLogConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			latency := time.Since(start)
+
+			if rw.status < http.StatusBadRequest && slices.Contains(cfg.healthCheckPaths, r.URL.Path) &&
+				(cfg.healthCheckRate <= 0 || rand.Float64() >= cfg.healthCheckRate) {
+				return
+			}
+
+			level := zerolog.InfoLevel
+			switch {
+			case rw.status >= http.StatusInternalServerError:
+				level = zerolog.ErrorLevel
+			case rw.status >= http.StatusBadRequest:
+				level = zerolog.WarnLevel
+			}
+
+			req := zerolog.Dict().
+				Str("requestMethod", r.Method).
+				Str("requestUrl", requestURL(r)).
+				Int("status", rw.status).
+				Str("responseSize", strconv.FormatInt(rw.size, 10)).
+				Str("userAgent", r.UserAgent()).
+				Str("remoteIp", remoteIP(r)).
+				Str("latency", FormatLatency(latency)).
+				Str("protocol", r.Proto).
+				Str("referer", r.Referer())
+			if r.ContentLength > 0 {
+				req.Str("requestSize", strconv.FormatInt(r.ContentLength, 10))
+			}
+
+			l := logger.With().Ctx(r.Context()).Logger()
+			l.WithLevel(level).Dict(HTTPRequestFieldName, req).Send()
+		})
+	}
+}
+
+// FormatLatency formats d as a duration in seconds with nanosecond precision,
+// such as "0.012345678s", the format of the latency field recognized by Cloud
+// Logging.
+func FormatLatency(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 9, 64) + "s"
+}
+
+// requestURL returns the absolute URL of the request, using the scheme set by
+// the Cloud Run front end in the X-Forwarded-Proto header.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// remoteIP returns the IP address of the client, which on Cloud Run is the
+// last address of the X-Forwarded-For header, appended by the Google Front
+// End. Earlier addresses are sent by the client and cannot be trusted.
+func remoteIP(r *http.Request) string {
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		xff := values[len(values)-1]
+		if i := strings.LastIndexByte(xff, ','); i >= 0 {
+			xff = xff[i+1:]
+		}
+		if ip := strings.TrimSpace(xff); ip != "" {
+			return ip
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// responseWriter records the status code and size of a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// Informational responses are followed by the final response.
+	if !w.wroteHeader && status >= http.StatusOK {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying response writer, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
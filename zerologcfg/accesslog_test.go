@@ -0,0 +1,52 @@
+package zerologcfg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRemoteIP(t *testing.T) {
+	tests := []struct {
+		name string
+		xff  []string
+		want string
+	}{
+		{
+			name: "no header",
+			want: "192.0.2.1",
+		},
+		{
+			name: "single address",
+			xff:  []string{"203.0.113.7"},
+			want: "203.0.113.7",
+		},
+		{
+			name: "spoofed address",
+			xff:  []string{"10.0.0.1, 203.0.113.7"},
+			want: "203.0.113.7",
+		},
+		{
+			name: "multiple headers",
+			xff:  []string{"10.0.0.1", "198.51.100.2,203.0.113.7"},
+			want: "203.0.113.7",
+		},
+		{
+			name: "empty last address",
+			xff:  []string{"203.0.113.7, "},
+			want: "192.0.2.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := remoteIP(r); got != tt.want {
+				t.Errorf("remoteIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+// Package compat configures zerolog globally for Google Cloud Logging when
+// imported, as zerologcfg did before Configure was introduced:
+//
+//	import _ "github.com/joaopenteado/runcfg/zerologcfg/compat"
+//
+// It is equivalent to calling zerologcfg.Configure with no options.
+package compat
+
+import "github.com/joaopenteado/runcfg/zerologcfg"
+
+func init() {
+	zerologcfg.Configure()
+}
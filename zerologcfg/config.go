@@ -0,0 +1,249 @@
+package zerologcfg
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DefaultLevelEnv is the environment variable the log level is read from by
+// default.
+const DefaultLevelEnv = "LOG_LEVEL"
+
+// SeverityFieldName is the field name of the log level recognized by Cloud
+// Logging.
+const SeverityFieldName = "severity"
+
+type config struct {
+	levelEnv   string
+	severity   func(zerolog.Level) string
+	timeFormat string
+	projectID  string
+	controller *LevelController
+	name       string
+	sampler    *Sampler
+}
+
+// Option configures Configure and New.
+type Option func(*config)
+
+// WithLevelEnv sets the environment variable the log level is read from.
+// Defaults to LOG_LEVEL. An empty name disables reading the level from the
+// environment. Invalid levels are ignored.
+func WithLevelEnv(name string) Option {
+	return func(cfg *config) {
+		cfg.levelEnv = name
+	}
+}
+
+// WithSeverityFunc sets the function mapping zerolog levels to Cloud Logging
+// severities. Defaults to Severity.
+func WithSeverityFunc(severity func(zerolog.Level) string) Option {
+	return func(cfg *config) {
+		cfg.severity = severity
+	}
+}
+
+// WithTimeFormat sets the format of the time field. Defaults to
+// time.RFC3339Nano.
+func WithTimeFormat(format string) Option {
+	return func(cfg *config) {
+		cfg.timeFormat = format
+	}
+}
+
+// WithProjectID adds the hook returned by Hook to loggers created by New, so
+// that entries are correlated with traces of the given project. Ignored by
+// Configure.
+func WithProjectID(projectID string) Option {
+	return func(cfg *config) {
+		cfg.projectID = projectID
+	}
+}
+
+// WithLevelController makes the level of loggers created by New controlled by
+// c, under the given name, as with [LevelController.Logger]. The level read
+// from the environment is then ignored.
+func WithLevelController(c *LevelController, name string) Option {
+	return func(cfg *config) {
+		cfg.controller = c
+		cfg.name = name
+	}
+}
+
+// WithSampler adds s to loggers created by New, so that their entries are
+// sampled. Ignored by Configure.
+func WithSampler(s *Sampler) Option {
+	return func(cfg *config) {
+		cfg.sampler = s
+	}
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		levelEnv:   DefaultLevelEnv,
+		severity:   Severity,
+		timeFormat: time.RFC3339Nano,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// level returns the level read from the environment, if any.
+func (cfg *config) level() (zerolog.Level, bool) {
+	if cfg.levelEnv == "" {
+		return zerolog.NoLevel, false
+	}
+
+	lvl, ok := os.LookupEnv(cfg.levelEnv)
+	if !ok {
+		return zerolog.NoLevel, false
+	}
+
+	level, err := zerolog.ParseLevel(lvl)
+	if err != nil {
+		return zerolog.NoLevel, false
+	}
+
+	return level, true
+}
+
+// Severity maps a zerolog level to a Cloud Logging severity.
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#logseverity
+func Severity(l zerolog.Level) string {
+	switch l {
+	case zerolog.TraceLevel:
+		return "DEFAULT"
+	case zerolog.DebugLevel:
+		return "DEBUG"
+	case zerolog.InfoLevel:
+		return "INFO"
+	case zerolog.WarnLevel:
+		return "WARNING"
+	case zerolog.ErrorLevel:
+		return "ERROR"
+	case zerolog.FatalLevel:
+		return "CRITICAL"
+	case zerolog.PanicLevel:
+		return "ALERT"
+	case zerolog.NoLevel:
+		return "DEFAULT"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// Configure modifies global zerolog settings to align with Google Cloud
+// Logging conventions. These changes affect all logging behavior globally in
+// the application, including libraries that also use zerolog. Use New to
+// configure a single logger instead.
+//
+// Specifically:
+//   - TimeFieldFormat is set to the time format, RFC3339Nano by default.
+//   - LevelFieldName is set to "severity" to match Google Cloud Logging's
+//     expected field name.
+//   - LevelFieldMarshalFunc is set to the severity function, mapping zerolog
+//     levels to Google Cloud Logging severity levels.
+//   - The global level is set to the level read from the LOG_LEVEL
+//     environment variable, if set.
+//   - InterfaceMarshalFunc marshals Redactable values in their redacted
+//     form.
+//
+// For more details, refer to:
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry
+func Configure(opts ...Option) {
+	cfg := newConfig(opts)
+
+	zerolog.TimeFieldFormat = cfg.timeFormat
+	zerolog.LevelFieldName = SeverityFieldName
+	zerolog.LevelFieldMarshalFunc = cfg.severity
+	redactInterfaces()
+
+	if level, ok := cfg.level(); ok {
+		zerolog.SetGlobalLevel(level)
+	}
+}
+
+// New returns a logger writing to w in the Cloud Logging structured logging
+// format without modifying global zerolog settings: the level is written as
+// the severity field and every entry has a time field. The logger level is
+// read from the LOG_LEVEL environment variable, if set. The only exception is
+// InterfaceMarshalFunc, which is wrapped as in Configure so that Redactable
+// values are logged in their redacted form.
+//
+// Do not add a timestamp with zerolog.Context.Timestamp, as New already adds
+// it.
+func New(w io.Writer, opts ...Option) zerolog.Logger {
+	cfg := newConfig(opts)
+	redactInterfaces()
+
+	logger := zerolog.New(&severityWriter{w: w, severity: cfg.severity}).
+		Hook(timeHook(cfg.timeFormat))
+
+	if cfg.controller != nil {
+		logger = cfg.controller.Logger(logger, cfg.name)
+	} else if level, ok := cfg.level(); ok {
+		logger = logger.Level(level)
+	}
+
+	if cfg.sampler != nil {
+		logger = logger.Hook(cfg.sampler)
+	}
+
+	if cfg.projectID != "" {
+		logger = logger.Hook(Hook(cfg.projectID))
+	}
+
+	return logger
+}
+
+// timeHook adds the time field with the given format, independently of
+// zerolog.TimeFieldFormat.
+type timeHook string
+
+func (h timeHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	e.Str("time", zerolog.TimestampFunc().Format(string(h)))
+}
+
+// severityWriter rewrites the level field, which zerolog always writes first,
+// as the severity field recognized by Cloud Logging.
+type severityWriter struct {
+	w        io.Writer
+	severity func(zerolog.Level) string
+}
+
+func (w *severityWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+func (w *severityWriter) WriteLevel(l zerolog.Level, p []byte) (int, error) {
+	prefix := `{"` + zerolog.LevelFieldName + `":"` + zerolog.LevelFieldMarshalFunc(l) + `"`
+	if l == zerolog.NoLevel || !bytes.HasPrefix(p, []byte(prefix)) {
+		return writeLevel(w.w, l, p)
+	}
+
+	rewritten := make([]byte, 0, len(p)+16)
+	rewritten = append(rewritten, `{"`+SeverityFieldName+`":"`+w.severity(l)+`"`...)
+	rewritten = append(rewritten, p[len(prefix):]...)
+
+	if _, err := writeLevel(w.w, l, rewritten); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeLevel writes p to w, preserving the level if w is a
+// zerolog.LevelWriter.
+func writeLevel(w io.Writer, l zerolog.Level, p []byte) (int, error) {
+	if lw, ok := w.(zerolog.LevelWriter); ok {
+		return lw.WriteLevel(l, p)
+	}
+	return w.Write(p)
+}
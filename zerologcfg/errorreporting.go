@@ -0,0 +1,213 @@
+package zerologcfg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// reportedErrorEventType is the @type of log entries that are always reported
+// to Error Reporting, even without a stack trace.
+// https://cloud.google.com/error-reporting/docs/formatting-error-messages
+const reportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// ServiceContext identifies the service reporting errors to Error Reporting.
+// Fill it from runcfg.Service.Name and Revision for services, or from
+// runcfg.Job.Name and Execution for jobs.
+type ServiceContext struct {
+	Service string
+	Version string
+}
+
+// ServiceContextFromEnv returns the service context of the Cloud Run service
+// or job, read from the K_SERVICE and K_REVISION or the CLOUD_RUN_JOB and
+// CLOUD_RUN_EXECUTION environment variables.
+func ServiceContextFromEnv() ServiceContext {
+	if service := os.Getenv("K_SERVICE"); service != "" {
+		return ServiceContext{Service: service, Version: os.Getenv("K_REVISION")}
+	}
+	return ServiceContext{Service: os.Getenv("CLOUD_RUN_JOB"), Version: os.Getenv("CLOUD_RUN_EXECUTION")}
+}
+
+type errorReportingHook struct {
+	sc ServiceContext
+}
+
+// ErrorReportingHook returns a hook that formats entries logged at ERROR
+// level or above, such as the ones logged with logger.Err(err) when err is not
+// nil, so that they are grouped by Error Reporting. It adds the @type,
+// serviceContext and stack_trace fields, the latter containing the message
+// followed by the stack trace of the caller.
+func ErrorReportingHook(sc ServiceContext) zerolog.Hook {
+	return &errorReportingHook{sc: sc}
+}
+
+func (h *errorReportingHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level < zerolog.ErrorLevel || level == zerolog.NoLevel || level == zerolog.Disabled {
+		return
+	}
+
+	// Panics are reported with the stack trace of where they happened.
+	if ctx := e.GetCtx(); ctx != nil && ctx.Value(reportedKey{}) != nil {
+		return
+	}
+
+	if msg == "" {
+		msg = "error"
+	}
+
+	addErrorReport(e, h.sc, msg+"\n\n"+callerStack())
+}
+
+// reportedKey is the context key marking entries already formatted for Error
+// Reporting.
+type reportedKey struct{}
+
+// addErrorReport adds the fields recognized by Error Reporting to e.
+func addErrorReport(e *zerolog.Event, sc ServiceContext, stackTrace string) {
+	service := zerolog.Dict().Str("service", sc.Service)
+	if sc.Version != "" {
+		service.Str("version", sc.Version)
+	}
+
+	e.Str("@type", reportedErrorEventType).
+		Dict("serviceContext", service).
+		Str("stack_trace", stackTrace)
+}
+
+// callerStack returns the stack trace of the goroutine in the format printed
+// by the Go runtime on panics, without the frames of zerolog and this
+// package.
+func callerStack() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	b.WriteString(goroutineHeader())
+	b.WriteByte('\n')
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/rs/zerolog") &&
+			!strings.HasPrefix(frame.Function, "github.com/joaopenteado/runcfg/zerologcfg.") {
+			b.WriteString(frame.Function)
+			b.WriteString("()\n\t")
+			b.WriteString(frame.File)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(frame.Line))
+			b.WriteByte('\n')
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// goroutineHeader returns the first line of the stack trace of the current
+// goroutine printed by the Go runtime, such as "goroutine 7 [running]:",
+// which Error Reporting expects before the frames.
+func goroutineHeader() string {
+	// The buffer fits the header of any goroutine ID; the rest of the stack
+	// trace is truncated.
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	header, _, _ := strings.Cut(string(buf), "\n")
+	return header
+}
+
+// reportPanic logs a recovered panic so that it is grouped by Error
+// Reporting, with the stack trace of where it happened.
+func reportPanic(ctx context.Context, logger *zerolog.Logger, sc ServiceContext, v any) {
+	msg := fmt.Sprintf("panic: %v", v)
+
+	e := logger.WithLevel(zerolog.PanicLevel).Ctx(context.WithValue(ctx, reportedKey{}, true))
+	addErrorReport(e, sc, msg+"\n\n"+string(debug.Stack()))
+	e.Msg(msg)
+}
+
+type recoverConfig struct {
+	sc ServiceContext
+}
+
+// RecoverOption configures RecoverHandler and RecoverAndReport.
+type RecoverOption func(*recoverConfig)
+
+// WithServiceContext sets the service context of reported panics, such as the
+// one passed to ErrorReportingHook. Defaults to ServiceContextFromEnv.
+func WithServiceContext(sc ServiceContext) RecoverOption {
+	return func(cfg *recoverConfig) {
+		cfg.sc = sc
+	}
+}
+
+func newRecoverConfig(opts []RecoverOption) *recoverConfig {
+	cfg := &recoverConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.sc == (ServiceContext{}) {
+		cfg.sc = ServiceContextFromEnv()
+	}
+	return cfg
+}
+
+// RecoverHandler returns a middleware that recovers from panics in next,
+// logs them with logger bound to the request context so that they are grouped
+// by Error Reporting, and responds with 500 Internal Server Error. The
+// serviceContext is read by ServiceContextFromEnv unless set with
+// WithServiceContext. Panics with http.ErrAbortHandler are not recovered.
+func RecoverHandler(logger zerolog.Logger, opts ...RecoverOption) func(next http.Handler) http.Handler {
+	sc := newRecoverConfig(opts).sc
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				ctx := r.Context()
+				l := logger.With().Ctx(ctx).Logger()
+				reportPanic(ctx, &l, sc, v)
+
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RecoverAndReport recovers from a panic, logs it with the global logger of
+// github.com/rs/zerolog/log so that it is grouped by Error Reporting, and
+// exits with status 2, as the Go runtime does for unrecovered panics. The
+// serviceContext is read by ServiceContextFromEnv unless set with
+// WithServiceContext. It must be deferred directly, typically at the top of
+// main in Cloud Run jobs:
+//
+//	defer zerologcfg.RecoverAndReport()
+//
+// Panics in other goroutines are not recovered.
+func RecoverAndReport(opts ...RecoverOption) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	reportPanic(context.Background(), &log.Logger, newRecoverConfig(opts).sc, v)
+	os.Exit(2)
+}
@@ -0,0 +1,60 @@
+package zerologcfg
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRecoverHandlerServiceContext(t *testing.T) {
+	t.Setenv("K_SERVICE", "env-service")
+	t.Setenv("K_REVISION", "env-service-00001")
+
+	tests := []struct {
+		name string
+		opts []RecoverOption
+		want string
+	}{
+		{
+			name: "from env",
+			want: `"serviceContext":{"service":"env-service","version":"env-service-00001"}`,
+		},
+		{
+			name: "from option",
+			opts: []RecoverOption{WithServiceContext(ServiceContext{Service: "api", Version: "v2"})},
+			want: `"serviceContext":{"service":"api","version":"v2"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			handler := RecoverHandler(New(&b), tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+			}
+			if !bytes.Contains(b.Bytes(), []byte(tt.want)) {
+				t.Errorf("logged %s, want %s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCallerStackHeader(t *testing.T) {
+	buf := make([]byte, 64)
+	want, _, _ := strings.Cut(string(buf[:runtime.Stack(buf, false)]), "\n")
+
+	got, _, _ := strings.Cut(callerStack(), "\n")
+	if got != want {
+		t.Errorf("callerStack() header = %q, want %q", got, want)
+	}
+}
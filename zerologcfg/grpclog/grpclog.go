@@ -0,0 +1,169 @@
+// Package grpclog provides gRPC interceptors that inject a zerolog logger into
+// the RPC context and log each RPC in the Cloud Logging structured logging
+// format configured by zerologcfg.
+package grpclog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/joaopenteado/runcfg/zerologcfg"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a gRPC interceptor that adds the zerolog
+// logger to the context of unary RPCs, bound to the RPC context the same way
+// as zerologcfg.Handler, and logs each RPC with its method, status code and latency.
+// Ensure that this interceptor is ran after any interceptor or stats handler
+// that injects tracing information to the context, such as otelgrpc.
+func UnaryServerInterceptor(logger zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		l := logger.With().Ctx(ctx).Logger()
+		resp, err := handler(l.WithContext(ctx), req)
+		logRPC(&l, "rpc completed", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the streaming RPC equivalent of
+// UnaryServerInterceptor.
+func StreamServerInterceptor(logger zerolog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := ss.Context()
+		l := logger.With().Ctx(ctx).Logger()
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: l.WithContext(ctx)})
+		logRPC(&l, "rpc completed", info.FullMethod, start, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a gRPC interceptor that logs each outgoing
+// unary RPC with its method, status code and latency, using logger bound to
+// the RPC context, which is also passed on to the invoker.
+func UnaryClientInterceptor(logger zerolog.Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		l := logger.With().Ctx(ctx).Logger()
+		err := invoker(l.WithContext(ctx), method, req, reply, cc, opts...)
+		logRPC(&l, "rpc call completed", method, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor is the streaming RPC equivalent of
+// UnaryClientInterceptor. Streams are logged once they end.
+func StreamClientInterceptor(logger zerolog.Logger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		l := logger.With().Ctx(ctx).Logger()
+		cs, err := streamer(l.WithContext(ctx), desc, cc, method, opts...)
+		if err != nil {
+			logRPC(&l, "rpc call completed", method, start, err)
+			return nil, err
+		}
+
+		return &clientStream{
+			ClientStream: cs,
+			desc:         desc,
+			done: func(err error) {
+				logRPC(&l, "rpc call completed", method, start, err)
+			},
+		}, nil
+	}
+}
+
+// logRPC logs a completed RPC with a severity depending on its status code.
+// The latency is formatted as in the access log written by
+// zerologcfg.AccessLog.
+func logRPC(logger *zerolog.Logger, msg, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	e := logger.WithLevel(codeLevel(code))
+	if err != nil {
+		e = e.Err(err)
+	}
+
+	e.Str("method", method).
+		Str("code", code.String()).
+		Str("latency", zerologcfg.FormatLatency(time.Since(start))).
+		Msg(msg)
+}
+
+// codeLevel maps a gRPC status code to a log level: INFO for success and
+// errors caused by the caller, WARNING for errors that may be transient and
+// ERROR for server errors.
+func codeLevel(code codes.Code) zerolog.Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound,
+		codes.AlreadyExists, codes.Unauthenticated:
+		return zerolog.InfoLevel
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return zerolog.WarnLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
+
+// serverStream wraps a grpc.ServerStream to override its context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// clientStream wraps a grpc.ClientStream to call done once the stream ends.
+type clientStream struct {
+	grpc.ClientStream
+	desc *grpc.StreamDesc
+	once sync.Once
+	done func(error)
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && !errors.Is(err, io.EOF) {
+		// io.EOF means the stream was aborted, and the status is only known
+		// to RecvMsg.
+		s.end(err)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case errors.Is(err, io.EOF):
+		s.end(nil)
+	case err != nil:
+		s.end(err)
+	case !s.desc.ServerStreams:
+		// Streams without server streaming end after a single message.
+		s.end(nil)
+	}
+	return err
+}
+
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.end(err)
+	}
+	return md, err
+}
+
+func (s *clientStream) end(err error) {
+	s.once.Do(func() { s.done(err) })
+}
@@ -0,0 +1,78 @@
+package grpclog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  string
+		wantLevel string
+	}{
+		{
+			name:      "ok",
+			wantCode:  "OK",
+			wantLevel: "info",
+		},
+		{
+			name:      "caller error",
+			err:       status.Error(codes.NotFound, "not found"),
+			wantCode:  "NotFound",
+			wantLevel: "info",
+		},
+		{
+			name:      "transient error",
+			err:       status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+			wantCode:  "DeadlineExceeded",
+			wantLevel: "warn",
+		},
+		{
+			name:      "server error",
+			err:       status.Error(codes.Internal, "internal"),
+			wantCode:  "Internal",
+			wantLevel: "error",
+		},
+	}
+
+	latency := regexp.MustCompile(`^\d+\.\d{9}s$`)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			interceptor := UnaryServerInterceptor(zerolog.New(&b))
+
+			info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Method"}
+			_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+				if zerolog.Ctx(ctx).GetLevel() == zerolog.Disabled {
+					t.Error("handler context has no logger")
+				}
+				return nil, tt.err
+			})
+			if err != tt.err {
+				t.Errorf("interceptor returned %v, want %v", err, tt.err)
+			}
+
+			var entry map[string]any
+			if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+				t.Fatalf("logged invalid JSON %s: %v", b.String(), err)
+			}
+			if entry["method"] != info.FullMethod || entry["code"] != tt.wantCode || entry["level"] != tt.wantLevel {
+				t.Errorf("logged %s, want method %s, code %s and level %s", b.String(), info.FullMethod, tt.wantCode, tt.wantLevel)
+			}
+			if s, _ := entry["latency"].(string); !latency.MatchString(s) {
+				t.Errorf("logged latency %v, want seconds such as 0.012345678s", entry["latency"])
+			}
+		})
+	}
+}
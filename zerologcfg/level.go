@@ -0,0 +1,377 @@
+package zerologcfg
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LevelController controls the log level of loggers at runtime, without
+// redeploying. Loggers are attached to it with [LevelController.Logger] or the
+// WithLevelController option of New, optionally under a name, such as the name
+// of a package, whose level can be overridden independently of the default
+// level.
+//
+// The level can be changed programmatically, through the HTTP handler returned
+// by [LevelController.Handler], with signals using
+// [LevelController.NotifySignals], or by watching a file with
+// [LevelController.WatchFile].
+//
+// The controller does not change the zerolog global level, so loggers not
+// attached to it are unaffected. Entries below the level of an attached logger
+// are discarded before being built, by a zerolog.Sampler set on the logger.
+// Replacing it with zerolog.Logger.Sample, or disabling sampling with
+// zerolog.DisableSampling, still discards them, but only once built. The
+// global level still applies, so levels below it have no effect.
+type LevelController struct {
+	mu        sync.Mutex
+	level     zerolog.Level
+	overrides map[string]zerolog.Level
+	reverts   map[string]*levelRevert
+}
+
+// levelRevert is a pending revert of a temporary level.
+type levelRevert struct {
+	timer   *time.Timer
+	prev    zerolog.Level
+	hadPrev bool
+}
+
+// NewLevelController returns a controller with the given default level.
+func NewLevelController(level zerolog.Level) *LevelController {
+	return &LevelController{
+		level:     level,
+		overrides: make(map[string]zerolog.Level),
+		reverts:   make(map[string]*levelRevert),
+	}
+}
+
+// Logger returns logger with its level controlled by c. If name is not empty,
+// overrides set for name take precedence over the default level.
+func (c *LevelController) Logger(logger zerolog.Logger, name string) zerolog.Logger {
+	// The logger level is the lowest one so that every level can be enabled
+	// later. The sampler discards entries below the level of the controller
+	// before they are built, and the hook in case the sampler is replaced.
+	return logger.Level(zerolog.TraceLevel).
+		Sample(levelSampler{c: c, name: name}).
+		Hook(levelHook{c: c, name: name})
+}
+
+// Level returns the default level.
+func (c *LevelController) Level() zerolog.Level {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.level
+}
+
+// LevelFor returns the effective level of loggers with the given name.
+func (c *LevelController) LevelFor(name string) zerolog.Level {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.levelFor(name)
+}
+
+func (c *LevelController) levelFor(name string) zerolog.Level {
+	if level, ok := c.overrides[name]; ok && name != "" {
+		return level
+	}
+	return c.level
+}
+
+// SetLevel sets the default level, canceling any temporary default level.
+func (c *LevelController) SetLevel(level zerolog.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cancelRevert("")
+	c.set("", level)
+}
+
+// SetOverride sets the level of loggers with the given name, canceling any
+// temporary level for it.
+func (c *LevelController) SetOverride(name string, level zerolog.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cancelRevert(name)
+	c.set(name, level)
+}
+
+// ClearOverride removes the level override of loggers with the given name,
+// which then use the default level.
+func (c *LevelController) ClearOverride(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cancelRevert(name)
+	delete(c.overrides, name)
+}
+
+// SetTemporaryLevel sets the level of loggers with the given name, or the
+// default level if name is empty, for duration d. Afterwards, the level
+// reverts to what it was before the first of any consecutive temporary
+// levels.
+func (c *LevelController) SetTemporaryLevel(name string, level zerolog.Level, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.reverts[name]
+	if ok {
+		r.timer.Stop()
+	} else {
+		r = &levelRevert{}
+		if name == "" {
+			r.prev, r.hadPrev = c.level, true
+		} else {
+			r.prev, r.hadPrev = c.overrides[name]
+		}
+		c.reverts[name] = r
+	}
+
+	c.set(name, level)
+
+	r.timer = time.AfterFunc(d, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.reverts[name] != r {
+			return
+		}
+		delete(c.reverts, name)
+
+		if r.hadPrev {
+			c.set(name, r.prev)
+		} else {
+			delete(c.overrides, name)
+		}
+	})
+}
+
+// Step changes the default level by delta, where a negative delta makes
+// logging more verbose, within the trace and panic levels.
+func (c *LevelController) Step(delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	level := int(c.level) + delta
+	level = max(level, int(zerolog.TraceLevel))
+	level = min(level, int(zerolog.PanicLevel))
+
+	c.cancelRevert("")
+	c.set("", zerolog.Level(level))
+}
+
+// set sets the level of loggers with the given name, or the default level if
+// name is empty. c.mu must be held.
+func (c *LevelController) set(name string, level zerolog.Level) {
+	if name == "" {
+		c.level = level
+	} else {
+		c.overrides[name] = level
+	}
+}
+
+// cancelRevert cancels the pending revert of a temporary level. c.mu must be
+// held.
+func (c *LevelController) cancelRevert(name string) {
+	if r, ok := c.reverts[name]; ok {
+		r.timer.Stop()
+		delete(c.reverts, name)
+	}
+}
+
+// enabled reports whether entries of the given level are enabled for loggers
+// with the given name.
+func (c *LevelController) enabled(name string, level zerolog.Level) bool {
+	return level == zerolog.NoLevel || level >= c.LevelFor(name)
+}
+
+// levelSampler skips entries below the level of the logger it is set on,
+// before they are built.
+type levelSampler struct {
+	c    *LevelController
+	name string
+}
+
+func (s levelSampler) Sample(level zerolog.Level) bool {
+	return s.c.enabled(s.name, level)
+}
+
+// levelHook discards entries below the level of the logger it is attached to,
+// once built.
+type levelHook struct {
+	c    *LevelController
+	name string
+}
+
+func (h levelHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if !h.c.enabled(h.name, level) {
+		e.Discard()
+	}
+}
+
+// levelState is the JSON representation of the levels of a controller.
+type levelState struct {
+	Level     string            `json:"level"`
+	Overrides map[string]string `json:"overrides,omitempty"`
+}
+
+func (c *LevelController) state() levelState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := levelState{Level: c.level.String()}
+	if len(c.overrides) > 0 {
+		s.Overrides = make(map[string]string, len(c.overrides))
+		for name, level := range c.overrides {
+			s.Overrides[name] = level.String()
+		}
+	}
+	return s
+}
+
+// BearerToken returns an authorization function for
+// [LevelController.Handler] accepting requests with the given bearer token in
+// the Authorization header. An empty token rejects every request.
+func BearerToken(token string) func(r *http.Request) bool {
+	want := []byte("Bearer " + token)
+	return func(r *http.Request) bool {
+		got := []byte(r.Header.Get("Authorization"))
+		return token != "" && subtle.ConstantTimeCompare(got, want) == 1
+	}
+}
+
+// Handler returns an admin HTTP handler for the levels of c. Requests not
+// authorized by authorize are rejected with 401 Unauthorized, and a nil
+// authorize rejects every request. Mount it on a path not exposed publicly,
+// and protect it with a secret, such as with BearerToken.
+//
+//   - GET responds with the current levels as JSON.
+//   - POST or PUT sets the level given by the level parameter. If the logger
+//     parameter is set, the level of loggers with that name is set instead of
+//     the default level. If the duration parameter is set, in the format of
+//     time.ParseDuration, the level is reverted after that duration.
+//   - DELETE clears the override of the logger given by the logger
+//     parameter.
+//
+// Parameters are read from the query string or a form-encoded body.
+func (c *LevelController) Handler(authorize func(r *http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorize == nil || !authorize(r) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		name := r.FormValue("logger")
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			level, err := zerolog.ParseLevel(r.FormValue("level"))
+			if err != nil || r.FormValue("level") == "" {
+				http.Error(w, "invalid level", http.StatusBadRequest)
+				return
+			}
+
+			if d := r.FormValue("duration"); d != "" {
+				duration, err := time.ParseDuration(d)
+				if err != nil || duration <= 0 {
+					http.Error(w, "invalid duration", http.StatusBadRequest)
+					return
+				}
+				c.SetTemporaryLevel(name, level, duration)
+			} else if name != "" {
+				c.SetOverride(name, level)
+			} else {
+				c.SetLevel(level)
+			}
+		case http.MethodDelete:
+			if name == "" {
+				http.Error(w, "missing logger", http.StatusBadRequest)
+				return
+			}
+			c.ClearOverride(name)
+		default:
+			w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c.state())
+	})
+}
+
+// WatchFile polls the file at path every interval until ctx is done, applying
+// its contents whenever they change, such as a secret or config volume
+// mounted on Cloud Run. Each line holds either a level, setting the default
+// level, or name=level, setting the level of loggers with that name.
+// Overrides not in the file are cleared, and invalid lines are ignored. A
+// missing file leaves the levels unchanged.
+func (c *LevelController) WatchFile(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		if data, err := os.ReadFile(path); err == nil && (last == nil || !bytes.Equal(data, last)) {
+			last = data
+			c.applyFile(data)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyFile applies the contents of a file watched by WatchFile.
+func (c *LevelController) applyFile(data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	overrides := make(map[string]zerolog.Level)
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, lvl, ok := strings.Cut(line, "=")
+		if !ok {
+			name, lvl = "", name
+		}
+
+		lvl = strings.TrimSpace(lvl)
+		level, err := zerolog.ParseLevel(lvl)
+		if err != nil || lvl == "" {
+			continue
+		}
+
+		if name = strings.TrimSpace(name); name == "" {
+			c.cancelRevert("")
+			c.level = level
+		} else {
+			overrides[name] = level
+		}
+	}
+
+	for name := range c.reverts {
+		if name != "" {
+			c.cancelRevert(name)
+		}
+	}
+	c.overrides = overrides
+}
@@ -0,0 +1,110 @@
+package zerologcfg
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLevelControllerLogger(t *testing.T) {
+	c := NewLevelController(zerolog.InfoLevel)
+	c.SetOverride("db", zerolog.DebugLevel)
+
+	tests := []struct {
+		name   string
+		logger func(w io.Writer) zerolog.Logger
+		want   []string
+	}{
+		{
+			name:   "default",
+			logger: func(w io.Writer) zerolog.Logger { return c.Logger(zerolog.New(w), "") },
+			want:   []string{"info", "warn"},
+		},
+		{
+			name:   "override",
+			logger: func(w io.Writer) zerolog.Logger { return c.Logger(zerolog.New(w), "db") },
+			want:   []string{"debug", "info", "warn"},
+		},
+		{
+			name: "sampler replaced",
+			logger: func(w io.Writer) zerolog.Logger {
+				return c.Logger(zerolog.New(w), "").Sample(&zerolog.BasicSampler{N: 1})
+			},
+			want: []string{"info", "warn"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			logger := tt.logger(&b)
+			logger.Trace().Msg("trace")
+			logger.Debug().Msg("debug")
+			logger.Info().Msg("info")
+			logger.Warn().Msg("warn")
+
+			var got []string
+			for line := range strings.Lines(b.String()) {
+				_, msg, _ := strings.Cut(line, `"message":"`)
+				msg, _, _ = strings.Cut(msg, `"`)
+				got = append(got, msg)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("logged %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelControllerDisabledEventNotBuilt(t *testing.T) {
+	c := NewLevelController(zerolog.InfoLevel)
+	logger := c.Logger(zerolog.New(io.Discard), "")
+
+	if e := logger.Debug(); e != nil {
+		t.Errorf("Debug() = %v, want a nil event below the level", e)
+	}
+	if e := logger.Info(); e == nil {
+		t.Error("Info() = nil, want an event at the level")
+	}
+}
+
+// BenchmarkLevelControllerDisabled compares the cost of entries below the
+// level of a controlled logger with that of a logger with a fixed level.
+func BenchmarkLevelControllerDisabled(b *testing.B) {
+	benchmarks := []struct {
+		name   string
+		logger zerolog.Logger
+	}{
+		{
+			name:   "fixed level",
+			logger: zerolog.New(io.Discard).Level(zerolog.InfoLevel),
+		},
+		{
+			name:   "controller",
+			logger: NewLevelController(zerolog.InfoLevel).Logger(zerolog.New(io.Discard), ""),
+		},
+		{
+			// The fallback once the sampler is replaced, which builds
+			// entries before discarding them.
+			name: "controller with hook only",
+			logger: NewLevelController(zerolog.InfoLevel).Logger(zerolog.New(io.Discard), "").
+				Sample(nil),
+		},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				bm.logger.Debug().
+					Str("user", "ana").
+					Int("attempt", 3).
+					Interface("payload", map[string]any{"items": []int{1, 2, 3}}).
+					Msg("debug")
+			}
+		})
+	}
+}
@@ -2,65 +2,14 @@ package zerologcfg
 
 import (
 	"net/http"
-	"os"
 	"runtime"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/trace"
 )
 
-func init() {
-	// This init function modifies global zerolog settings to align with Google
-	// Cloud Logging conventions.
-	// These changes affect all logging behavior globally in the application,
-	// which may have side effects on downstream logging if other parts of the
-	// application or libraries also use zerolog.
-	//
-	// Specifically:
-	// - TimeFieldFormat is set to RFC3339Nano for precise timestamp formatting.
-	// - LevelFieldName is set to "severity" to match Google Cloud Logging's
-	//   expected field name.
-	// - LevelFieldMarshalFunc is customized to map zerolog levels to Google
-	//   Cloud Logging severity levels.
-	//
-	// For more details, refer to:
-	// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry
-
-	zerolog.TimeFieldFormat = time.RFC3339Nano
-	zerolog.LevelFieldName = "severity"
-	zerolog.LevelFieldMarshalFunc = func(l zerolog.Level) string {
-		switch l {
-		case zerolog.TraceLevel:
-			return "DEFAULT"
-		case zerolog.DebugLevel:
-			return "DEBUG"
-		case zerolog.InfoLevel:
-			return "INFO"
-		case zerolog.WarnLevel:
-			return "WARNING"
-		case zerolog.ErrorLevel:
-			return "ERROR"
-		case zerolog.FatalLevel:
-			return "CRITICAL"
-		case zerolog.PanicLevel:
-			return "ALERT"
-		case zerolog.NoLevel:
-			return "DEFAULT"
-		default:
-			return "DEFAULT"
-		}
-	}
-
-	if lvl, ok := os.LookupEnv("LOG_LEVEL"); ok {
-		if lvl, err := zerolog.ParseLevel(lvl); err == nil {
-			zerolog.SetGlobalLevel(lvl)
-		}
-	}
-}
-
 type cloudLoggingHook struct {
 	ProjectID string
 }
@@ -0,0 +1,273 @@
+package zerologcfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// RedactedValue replaces redacted values.
+const RedactedValue = "[REDACTED]"
+
+var (
+	// EmailPattern matches email addresses.
+	EmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+	// CardNumberPattern matches payment card numbers of 13 to 19 digits,
+	// optionally separated by spaces or dashes. A RedactWriter only redacts
+	// its matches that pass the Luhn checksum, which every card number does,
+	// so that most other long numbers, such as order numbers, are kept.
+	CardNumberPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+
+	// TokenPattern matches bearer tokens and JSON Web Tokens, such as the
+	// OAuth 2.0 access tokens and ID tokens of Google Cloud.
+	TokenPattern = regexp.MustCompile(`(?i:bearer\s+)[A-Za-z0-9._~+/-]+=*|\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*|\bya29\.[A-Za-z0-9._-]+`)
+)
+
+// DefaultRedactedFields are the names of the fields whose values are redacted
+// by a RedactWriter unless configured otherwise.
+var DefaultRedactedFields = []string{"password", "secret", "token", "authorization", "cookie", "apiKey"}
+
+// DefaultRedactionPatterns are the patterns redacted from string values by a
+// RedactWriter unless configured otherwise.
+var DefaultRedactionPatterns = []*regexp.Regexp{EmailPattern, CardNumberPattern, TokenPattern}
+
+type redactConfig struct {
+	fields   []string
+	patterns []*regexp.Regexp
+}
+
+// RedactOption configures a RedactWriter.
+type RedactOption func(*redactConfig)
+
+// WithRedactedFields sets the names of the fields whose values are redacted,
+// at any depth. Names are matched exactly. Defaults to DefaultRedactedFields.
+func WithRedactedFields(names ...string) RedactOption {
+	return func(cfg *redactConfig) {
+		cfg.fields = names
+	}
+}
+
+// WithRedactionPatterns sets the patterns redacted from string values, at any
+// depth, including the message. Defaults to DefaultRedactionPatterns.
+func WithRedactionPatterns(patterns ...*regexp.Regexp) RedactOption {
+	return func(cfg *redactConfig) {
+		cfg.patterns = patterns
+	}
+}
+
+// RedactWriter is a writer redacting sensitive data from JSON log entries
+// before they are written: values of fields with redacted names are replaced
+// by RedactedValue, and so are matches of the redaction patterns in string
+// values. The severity, time, trace and sourceLocation fields are never
+// redacted. Entries that are not JSON objects are written unchanged.
+//
+// Use it together with Redactable, for types that know which of their data is
+// sensitive.
+type RedactWriter struct {
+	w        io.Writer
+	fields   []string
+	keys     [][]byte
+	patterns []*regexp.Regexp
+}
+
+// NewRedactWriter returns a RedactWriter writing to w. Pass it to New, or to
+// zerolog.New after Configure. Like Configure and New, it wraps
+// InterfaceMarshalFunc so that Redactable values are logged in their redacted
+// form.
+func NewRedactWriter(w io.Writer, opts ...RedactOption) *RedactWriter {
+	redactInterfaces()
+
+	cfg := &redactConfig{
+		fields:   DefaultRedactedFields,
+		patterns: DefaultRedactionPatterns,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	keys := make([][]byte, len(cfg.fields))
+	for i, name := range cfg.fields {
+		keys[i], _ = marshalString(name)
+	}
+
+	return &RedactWriter{w: w, fields: cfg.fields, keys: keys, patterns: cfg.patterns}
+}
+
+func (w *RedactWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *RedactWriter) WriteLevel(l zerolog.Level, p []byte) (int, error) {
+	if !w.sensitive(p) {
+		return writeLevel(w.w, l, p)
+	}
+
+	fields, ok := parseEntry(p)
+	if !ok {
+		return writeLevel(w.w, l, p)
+	}
+
+	for i := range fields {
+		if !fields[i].protected {
+			fields[i].value = w.redactField(fields[i].name, fields[i].value)
+		}
+	}
+
+	if _, err := writeLevel(w.w, l, encodeEntry(fields, nil)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// sensitive reports whether p may contain data to redact, so that other
+// entries are written without being parsed.
+func (w *RedactWriter) sensitive(p []byte) bool {
+	for _, key := range w.keys {
+		if bytes.Contains(p, key) {
+			return true
+		}
+	}
+	for _, re := range w.patterns {
+		if re.Match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactField redacts the JSON value of the field with the given name.
+func (w *RedactWriter) redactField(name string, value []byte) []byte {
+	if slices.Contains(w.fields, name) {
+		redacted, _ := marshalString(RedactedValue)
+		return redacted
+	}
+	return w.redactValue(value)
+}
+
+// redactValue redacts the JSON value, recursing into objects and arrays.
+func (w *RedactWriter) redactValue(value []byte) []byte {
+	switch value[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return value
+		}
+		redacted := s
+		for _, re := range w.patterns {
+			redacted = re.ReplaceAllStringFunc(redacted, func(match string) string {
+				if re == CardNumberPattern && !luhnValid(match) {
+					return match
+				}
+				return RedactedValue
+			})
+		}
+		if redacted == s {
+			return value
+		}
+		b, _ := marshalString(redacted)
+		return b
+
+	case '{':
+		fields, ok := parseEntry(value)
+		if !ok {
+			return value
+		}
+		for i := range fields {
+			fields[i].value = w.redactField(fields[i].name, fields[i].value)
+		}
+		return bytes.TrimSuffix(encodeEntry(fields, nil), []byte("\n"))
+
+	case '[':
+		var elems []json.RawMessage
+		if err := json.Unmarshal(value, &elems); err != nil {
+			return value
+		}
+		var b bytes.Buffer
+		b.WriteByte('[')
+		for i, elem := range elems {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.Write(w.redactValue(elem))
+		}
+		b.WriteByte(']')
+		return b.Bytes()
+
+	default:
+		return value
+	}
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum, ignoring
+// any other character.
+func luhnValid(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// Redactable is implemented by types holding sensitive data, such as
+// credentials or personal information, to be logged in a redacted form. Log
+// them with Interface and Redact, which works with any logger:
+//
+//	logger.Info().Interface("user", zerologcfg.Redact(user)).Msg("signed in")
+//
+// Once Configure, New or NewRedactWriter is called, values logged with
+// Interface are replaced by the result of their Redact method even without
+// Redact, whichever logger logs them. Types implementing
+// zerolog.LogObjectMarshaler can redact their sensitive fields in
+// MarshalZerologObject instead.
+type Redactable interface {
+	Redact() any
+}
+
+// Redact returns the result of the Redact method of v if v is Redactable, and
+// v otherwise.
+func Redact(v any) any {
+	if r, ok := v.(Redactable); ok {
+		return r.Redact()
+	}
+	return v
+}
+
+// redactableMarshalFunc returns a zerolog.InterfaceMarshalFunc that marshals
+// Redactable values in their redacted form with marshal.
+func redactableMarshalFunc(marshal func(v any) ([]byte, error)) func(v any) ([]byte, error) {
+	return func(v any) ([]byte, error) {
+		return marshal(Redact(v))
+	}
+}
+
+// redactOnce guards redactInterfaces.
+var redactOnce sync.Once
+
+// redactInterfaces wraps zerolog.InterfaceMarshalFunc with
+// redactableMarshalFunc once, however many times it is called. zerolog has no
+// per-logger marshal function and Redactable values are already encoded when
+// hooks and writers see them, so this is the only way to redact them without
+// Redact. Other values are marshaled unchanged.
+func redactInterfaces() {
+	redactOnce.Do(func() {
+		zerolog.InterfaceMarshalFunc = redactableMarshalFunc(zerolog.InterfaceMarshalFunc)
+	})
+}
@@ -0,0 +1,174 @@
+package zerologcfg
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRedactWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+		want  string
+	}{
+		{
+			name:  "nothing to redact",
+			entry: `{"severity":"INFO","message":"hello"}`,
+			want:  `{"severity":"INFO","message":"hello"}`,
+		},
+		{
+			name:  "redacted field",
+			entry: `{"severity":"INFO","password":"hunter2","message":"hello"}`,
+			want:  `{"severity":"INFO","password":"[REDACTED]","message":"hello"}`,
+		},
+		{
+			name:  "nested redacted field",
+			entry: `{"user":{"name":"ana","token":{"value":"abc"}},"items":[{"secret":1}]}`,
+			want:  `{"user":{"name":"ana","token":"[REDACTED]"},"items":[{"secret":"[REDACTED]"}]}`,
+		},
+		{
+			name:  "email",
+			entry: `{"message":"sent to ana@example.com"}`,
+			want:  `{"message":"sent to [REDACTED]"}`,
+		},
+		{
+			name:  "card number",
+			entry: `{"message":"paid with 4111 1111 1111 1111"}`,
+			want:  `{"message":"paid with [REDACTED]"}`,
+		},
+		{
+			name:  "card number with dashes",
+			entry: `{"card":"5500-0000-0000-0004"}`,
+			want:  `{"card":"[REDACTED]"}`,
+		},
+		{
+			name:  "order number",
+			entry: `{"message":"order 1234567890123"}`,
+			want:  `{"message":"order 1234567890123"}`,
+		},
+		{
+			name:  "number failing the luhn checksum",
+			entry: `{"message":"paid with 4111 1111 1111 1112"}`,
+			want:  `{"message":"paid with 4111 1111 1111 1112"}`,
+		},
+		{
+			name:  "short number",
+			entry: `{"message":"call 555 0100"}`,
+			want:  `{"message":"call 555 0100"}`,
+		},
+		{
+			name:  "bearer token",
+			entry: `{"header":"Bearer abc.def-123"}`,
+			want:  `{"header":"[REDACTED]"}`,
+		},
+		{
+			name:  "protected field",
+			entry: `{"severity":"INFO","logging.googleapis.com/trace":"projects/p/traces/4111111111111111"}`,
+			want:  `{"severity":"INFO","logging.googleapis.com/trace":"projects/p/traces/4111111111111111"}`,
+		},
+		{
+			name:  "field name in a value",
+			entry: `{"message":"wrong password"}`,
+			want:  `{"message":"wrong password"}`,
+		},
+		{
+			name:  "not a JSON object",
+			entry: `password: hunter2`,
+			want:  `password: hunter2`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// zerolog terminates every entry with a newline.
+			entry := tt.entry + "\n"
+
+			var b bytes.Buffer
+			n, err := NewRedactWriter(&b).Write([]byte(entry))
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if n != len(entry) {
+				t.Errorf("Write() = %d, want %d", n, len(entry))
+			}
+			if got := strings.TrimSuffix(b.String(), "\n"); got != tt.want {
+				t.Errorf("Write() wrote %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"4111111111111111", true},
+		{"4111 1111 1111 1111", true},
+		{"378282246310005", true},
+		{"4111111111111112", false},
+		{"1234567890123", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := luhnValid(tt.s); got != tt.want {
+			t.Errorf("luhnValid(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+type credentials struct {
+	User, Password string
+}
+
+func (c credentials) Redact() any {
+	return credentials{User: c.User, Password: RedactedValue}
+}
+
+func TestRedact(t *testing.T) {
+	user := credentials{User: "ana", Password: "hunter2"}
+	const want = `"credentials":{"User":"ana","Password":"[REDACTED]"}`
+
+	tests := []struct {
+		name  string
+		value any
+		new   func(w io.Writer) zerolog.Logger
+	}{
+		{
+			name:  "Redact",
+			value: Redact(user),
+			new:   func(w io.Writer) zerolog.Logger { return zerolog.New(w) },
+		},
+		{
+			name:  "New",
+			value: user,
+			new:   func(w io.Writer) zerolog.Logger { return New(w) },
+		},
+		{
+			name:  "RedactWriter",
+			value: user,
+			new:   func(w io.Writer) zerolog.Logger { return zerolog.New(NewRedactWriter(w)) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			logger := tt.new(&b)
+			logger.Info().Interface("credentials", tt.value).Send()
+
+			if !bytes.Contains(b.Bytes(), []byte(want)) {
+				t.Errorf("logged %s, want redacted credentials", b.String())
+			}
+		})
+	}
+
+	if got := Redact("plain"); got != "plain" {
+		t.Errorf("Redact() = %v, want plain", got)
+	}
+}
@@ -0,0 +1,205 @@
+package zerologcfg
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultSampling is the sampling applied by a Sampler to TRACE, DEBUG and
+// INFO entries unless configured otherwise: at most 10 entries per second with
+// the same message.
+var DefaultSampling = zerolog.BurstSampler{Burst: 10, Period: time.Second}
+
+// DefaultMaxSamplingKeys is the default number of distinct messages sampled
+// independently by a Sampler.
+const DefaultMaxSamplingKeys = 1000
+
+type samplingConfig struct {
+	levels  map[zerolog.Level]zerolog.BurstSampler
+	maxKeys int
+}
+
+// SamplingOption configures a Sampler.
+type SamplingOption func(*samplingConfig)
+
+// WithLevelSampling sets the sampling of entries at the given level, which is
+// applied independently to each message as by a zerolog.BurstSampler: burst
+// entries are logged per period, and the remaining ones are logged only if
+// accepted by NextSampler. A zero BurstSampler disables sampling at that
+// level. Entries at ERROR level or above are never sampled, so the option is
+// ignored for them.
+func WithLevelSampling(level zerolog.Level, sampling zerolog.BurstSampler) SamplingOption {
+	return func(cfg *samplingConfig) {
+		if level >= zerolog.ErrorLevel {
+			return
+		}
+		if sampling.Burst == 0 && sampling.NextSampler == nil {
+			delete(cfg.levels, level)
+			return
+		}
+		cfg.levels[level] = zerolog.BurstSampler{
+			Burst:       sampling.Burst,
+			Period:      sampling.Period,
+			NextSampler: sampling.NextSampler,
+		}
+	}
+}
+
+// WithMaxSamplingKeys sets the number of distinct messages sampled
+// independently. Once reached, entries with other messages share the sampling
+// of their level. Defaults to DefaultMaxSamplingKeys.
+func WithMaxSamplingKeys(n int) SamplingOption {
+	return func(cfg *samplingConfig) {
+		cfg.maxKeys = n
+	}
+}
+
+// samplingKey identifies entries sampled together.
+type samplingKey struct {
+	level zerolog.Level
+	msg   string
+}
+
+// samplingState is the sampling state of entries with the same key.
+type samplingState struct {
+	sampler    *zerolog.BurstSampler
+	suppressed atomic.Uint64
+}
+
+// Sampler is a hook that samples log entries to control the cost of Cloud
+// Logging in chatty services. Entries at ERROR level or above, entries without
+// a level and entries of sampled traces, as reported by the
+// logging.googleapis.com/trace_sampled field, are never dropped. The other
+// entries are sampled per level and message, as configured by
+// WithLevelSampling.
+//
+// Use [Sampler.Summarize] to periodically log how many entries were
+// suppressed.
+type Sampler struct {
+	mu      sync.Mutex
+	levels  map[zerolog.Level]zerolog.BurstSampler
+	maxKeys int
+	states  map[samplingKey]*samplingState
+}
+
+// NewSampler returns a Sampler applying DefaultSampling to TRACE, DEBUG and
+// INFO entries, unless configured otherwise.
+func NewSampler(opts ...SamplingOption) *Sampler {
+	cfg := &samplingConfig{
+		levels: map[zerolog.Level]zerolog.BurstSampler{
+			zerolog.TraceLevel: DefaultSampling,
+			zerolog.DebugLevel: DefaultSampling,
+			zerolog.InfoLevel:  DefaultSampling,
+		},
+		maxKeys: DefaultMaxSamplingKeys,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return &Sampler{
+		levels:  cfg.levels,
+		maxKeys: cfg.maxKeys,
+		states:  make(map[samplingKey]*samplingState),
+	}
+}
+
+func (s *Sampler) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level >= zerolog.ErrorLevel {
+		return
+	}
+
+	if ctx := e.GetCtx(); ctx != nil {
+		if ctx.Value(summaryKey{}) != nil || trace.SpanContextFromContext(ctx).IsSampled() {
+			return
+		}
+	}
+
+	state := s.state(level, msg)
+	if state != nil && !state.sampler.Sample(level) {
+		state.suppressed.Add(1)
+		e.Discard()
+	}
+}
+
+// state returns the sampling state of entries with the given level and
+// message, or nil if the level is not sampled.
+func (s *Sampler) state(level zerolog.Level, msg string) *samplingState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sampling, ok := s.levels[level]
+	if !ok {
+		return nil
+	}
+
+	key := samplingKey{level: level, msg: msg}
+	if state, ok := s.states[key]; ok {
+		return state
+	}
+	if len(s.states) >= s.maxKeys {
+		key.msg = ""
+		if state, ok := s.states[key]; ok {
+			return state
+		}
+	}
+
+	state := &samplingState{sampler: &sampling}
+	s.states[key] = state
+	return state
+}
+
+// summaryKey is the context key marking summaries logged by Summarize, which
+// are never sampled.
+type summaryKey struct{}
+
+// Summarize logs with logger, every interval until ctx is done and once more
+// afterwards, an entry for each message with suppressed entries since the
+// previous summary, such as "25 similar entries suppressed". Summaries have
+// the level of the suppressed entries and their message in the
+// suppressedMessage field, and are never sampled.
+func (s *Sampler) Summarize(ctx context.Context, logger zerolog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.summarize(&logger)
+			return
+		case <-ticker.C:
+			s.summarize(&logger)
+		}
+	}
+}
+
+func (s *Sampler) summarize(logger *zerolog.Logger) {
+	type summary struct {
+		key samplingKey
+		n   uint64
+	}
+
+	s.mu.Lock()
+	var summaries []summary
+	for key, state := range s.states {
+		if n := state.suppressed.Swap(0); n > 0 {
+			summaries = append(summaries, summary{key: key, n: n})
+		}
+	}
+	s.mu.Unlock()
+
+	ctx := context.WithValue(context.Background(), summaryKey{}, true)
+	for _, sum := range summaries {
+		logger.WithLevel(sum.key.level).Ctx(ctx).
+			Str("suppressedMessage", sum.key.msg).
+			Uint64("suppressedCount", sum.n).
+			Msg(strconv.FormatUint(sum.n, 10) + " similar entries suppressed")
+	}
+}
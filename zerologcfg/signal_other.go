@@ -0,0 +1,11 @@
+//go:build !unix
+
+package zerologcfg
+
+import "context"
+
+// NotifySignals changes the default level of c on signals until ctx is done:
+// SIGUSR1 makes logging one level more verbose and SIGUSR2 one level less
+// verbose. Signals are only available on Unix systems; elsewhere,
+// NotifySignals returns immediately.
+func (c *LevelController) NotifySignals(ctx context.Context) {}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package zerologcfg
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// NotifySignals changes the default level of c on signals until ctx is done:
+// SIGUSR1 makes logging one level more verbose and SIGUSR2 one level less
+// verbose. Signals are only available on Unix systems; elsewhere,
+// NotifySignals returns immediately.
+func (c *LevelController) NotifySignals(ctx context.Context) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(sigs)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigs:
+			if sig == syscall.SIGUSR1 {
+				c.Step(-1)
+			} else {
+				c.Step(1)
+			}
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package zerologcfg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// DefaultMaxEntrySize is the default maximum size of a log entry written by a
+// SizeLimitWriter. Cloud Logging rejects entries over 256 KB, including the
+// metadata it adds, so the default leaves some room for it.
+const DefaultMaxEntrySize = 250 * 1024
+
+// TruncatedFieldName is the field listing the names of the fields truncated
+// by a SizeLimitWriter.
+const TruncatedFieldName = "truncated"
+
+// meterName is the instrumentation scope name of the metrics of this package.
+const meterName = "github.com/joaopenteado/runcfg/zerologcfg"
+
+// protectedFields are the fields never truncated by a SizeLimitWriter, in
+// addition to the level field.
+var protectedFields = []string{
+	SeverityFieldName,
+	"time",
+	"logging.googleapis.com/trace",
+	"logging.googleapis.com/spanId",
+	"logging.googleapis.com/trace_sampled",
+	"logging.googleapis.com/sourceLocation",
+}
+
+// truncationSuffix is appended to truncated field values.
+const truncationSuffix = "…"
+
+type sizeLimitConfig struct {
+	maxSize       int
+	meterProvider metric.MeterProvider
+}
+
+// SizeLimitOption configures a SizeLimitWriter.
+type SizeLimitOption func(*sizeLimitConfig)
+
+// WithMaxEntrySize sets the maximum size of a log entry, in bytes. Defaults to
+// DefaultMaxEntrySize.
+func WithMaxEntrySize(size int) SizeLimitOption {
+	return func(cfg *sizeLimitConfig) {
+		cfg.maxSize = size
+	}
+}
+
+// WithMeterProvider sets the meter provider of the counter of truncated
+// entries. Defaults to the global meter provider, which otelcfg sets up.
+func WithMeterProvider(mp metric.MeterProvider) SizeLimitOption {
+	return func(cfg *sizeLimitConfig) {
+		cfg.meterProvider = mp
+	}
+}
+
+// SizeLimitWriter is a writer enforcing a maximum size on JSON log entries,
+// so that they are neither rejected by Cloud Logging nor split into unparsed
+// text. Fields of larger entries are truncated, largest first, and listed in
+// the truncated field. The message is only truncated if truncating the other
+// fields is not enough, and the severity, time, trace and sourceLocation
+// fields are never truncated. Entries that are not JSON objects are written
+// unchanged.
+//
+// Truncated entries are counted by the log.entries.truncated metric.
+type SizeLimitWriter struct {
+	w         io.Writer
+	maxSize   int
+	truncated metric.Int64Counter
+}
+
+// NewSizeLimitWriter returns a SizeLimitWriter writing to w. Pass it to New,
+// or to zerolog.New after Configure.
+func NewSizeLimitWriter(w io.Writer, opts ...SizeLimitOption) *SizeLimitWriter {
+	cfg := &sizeLimitConfig{
+		maxSize:       DefaultMaxEntrySize,
+		meterProvider: otel.GetMeterProvider(),
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// The counter is a no-op if it cannot be created.
+	truncated, _ := cfg.meterProvider.Meter(meterName).Int64Counter("log.entries.truncated",
+		metric.WithDescription("Number of log entries truncated to fit the maximum entry size."),
+		metric.WithUnit("{entry}"))
+
+	return &SizeLimitWriter{w: w, maxSize: cfg.maxSize, truncated: truncated}
+}
+
+func (w *SizeLimitWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *SizeLimitWriter) WriteLevel(l zerolog.Level, p []byte) (int, error) {
+	if len(p) <= w.maxSize {
+		return writeLevel(w.w, l, p)
+	}
+
+	truncated, ok := truncateEntry(p, w.maxSize)
+	if !ok {
+		return writeLevel(w.w, l, p)
+	}
+	w.truncated.Add(context.Background(), 1)
+
+	if _, err := writeLevel(w.w, l, truncated); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// entryField is a field of a log entry, with its key and value in JSON.
+type entryField struct {
+	key, value []byte
+	name       string
+	protected  bool
+	exhausted  bool
+}
+
+// truncateEntry truncates the fields of the JSON object in p until it fits in
+// maxSize, reporting whether p is a JSON object. The largest fields are
+// truncated first, down to a common size, and the message is only truncated
+// once no other field can be.
+func truncateEntry(p []byte, maxSize int) ([]byte, bool) {
+	fields, ok := parseEntry(p)
+	if !ok {
+		return nil, false
+	}
+
+	var names []string
+	for {
+		out := encodeEntry(fields, names)
+		if len(out) <= maxSize {
+			return out, true
+		}
+
+		candidates := truncatable(fields)
+		if len(candidates) == 0 {
+			return out, true
+		}
+
+		limit := truncationLimit(candidates, len(out)-maxSize)
+		for _, f := range candidates {
+			if len(f.value) <= limit {
+				continue
+			}
+
+			value := truncateValue(f.value, limit)
+			if len(value) >= len(f.value) {
+				f.exhausted = true
+				continue
+			}
+			f.value = value
+
+			if !slices.Contains(names, f.name) {
+				names = append(names, f.name)
+			}
+		}
+	}
+}
+
+// truncatable returns the fields that can still be truncated. The message is
+// only returned once no other field can be truncated.
+func truncatable(fields []entryField) []*entryField {
+	var others, message []*entryField
+	for i := range fields {
+		f := &fields[i]
+		switch {
+		case f.protected || f.exhausted:
+		case f.name == zerolog.MessageFieldName:
+			message = append(message, f)
+		default:
+			others = append(others, f)
+		}
+	}
+
+	if len(others) > 0 {
+		return others
+	}
+	return message
+}
+
+// truncationLimit returns the largest size such that truncating every field
+// larger than it to that size shortens the entry by at least excess bytes. It
+// is always smaller than the largest field.
+func truncationLimit(fields []*entryField, excess int) int {
+	sizes := make([]int, len(fields))
+	for i, f := range fields {
+		sizes[i] = len(f.value)
+	}
+	slices.Sort(sizes)
+	slices.Reverse(sizes)
+
+	var removed int
+	for i, size := range sizes {
+		var next int
+		if i+1 < len(sizes) {
+			next = sizes[i+1]
+		}
+
+		// Truncating the i+1 largest fields from size down to next.
+		n := i + 1
+		if removed+n*(size-next) >= excess {
+			return size - (excess-removed+n-1)/n
+		}
+		removed += n * (size - next)
+	}
+	return 0
+}
+
+// parseEntry splits the JSON object in p into its fields.
+func parseEntry(p []byte) ([]entryField, bool) {
+	if !json.Valid(p) {
+		return nil, false
+	}
+
+	// Read the fields in order, as maps do not preserve it.
+	dec := json.NewDecoder(bytes.NewReader(p))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+
+	var fields []entryField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		name, _ := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, false
+		}
+
+		key, _ := marshalString(name)
+		fields = append(fields, entryField{
+			key:       key,
+			value:     value,
+			name:      name,
+			protected: name == zerolog.LevelFieldName || slices.Contains(protectedFields, name),
+		})
+	}
+	return fields, true
+}
+
+// encodeEntry encodes fields as a JSON object followed by a newline, listing
+// the names of truncated fields in the truncated field.
+func encodeEntry(fields []entryField, truncated []string) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(f.key)
+		b.WriteByte(':')
+		b.Write(f.value)
+	}
+	if len(truncated) > 0 {
+		names, _ := json.Marshal(truncated)
+		b.WriteString(`,"` + TruncatedFieldName + `":`)
+		b.Write(names)
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// truncateValue returns a JSON string of at most size bytes, if possible,
+// holding the beginning of value, or of its JSON encoding if it is not a
+// string, followed by an ellipsis.
+func truncateValue(value []byte, size int) []byte {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		s = string(value)
+	}
+
+	// Escaping may make the encoded string longer than s, so keep cutting it
+	// until it fits.
+	n := min(len(s), max(size-len(`""`)-len(truncationSuffix), 0))
+	for {
+		for n > 0 && n < len(s) && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		truncated, err := marshalString(s[:n] + truncationSuffix)
+		if err != nil || len(truncated) <= size || n == 0 {
+			return truncated
+		}
+		n = max(n-(len(truncated)-size), 0)
+	}
+}
+
+// marshalString encodes s as a JSON string without escaping HTML characters.
+func marshalString(s string) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,218 @@
+package zerologcfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+func TestTruncateEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		maxSize int
+
+		// unchanged are the fields expected to keep their value.
+		unchanged []string
+
+		// wantTruncated are the expected names in the truncated field.
+		wantTruncated []string
+
+		// wantPrefix are the expected beginnings of truncated string fields.
+		wantPrefix map[string]string
+
+		// oversized is set if the entry cannot fit in maxSize.
+		oversized bool
+	}{
+		{
+			name:      "fits",
+			entry:     `{"severity":"INFO","message":"hello"}`,
+			maxSize:   100,
+			unchanged: []string{"severity", "message"},
+		},
+		{
+			name: "largest field first",
+			entry: `{"severity":"INFO","time":"2026-10-18T12:00:00Z","user":"ana","payload":"` +
+				strings.Repeat("p", 300) + `","message":"hello world"}`,
+			maxSize:       200,
+			unchanged:     []string{"severity", "time", "user", "message"},
+			wantTruncated: []string{"payload"},
+			wantPrefix:    map[string]string{"payload": "ppppp"},
+		},
+		{
+			name: "largest fields down to a common size",
+			entry: `{"severity":"INFO","a":"` + strings.Repeat("a", 300) + `","b":"` +
+				strings.Repeat("b", 100) + `","c":"` + strings.Repeat("c", 20) + `","message":"hello world"}`,
+			maxSize:       250,
+			unchanged:     []string{"severity", "c", "message"},
+			wantTruncated: []string{"a", "b"},
+			wantPrefix:    map[string]string{"a": strings.Repeat("a", 50), "b": strings.Repeat("b", 50)},
+		},
+		{
+			name: "message last",
+			entry: `{"severity":"INFO","time":"2026-10-18T12:00:00Z","user":"` + strings.Repeat("u", 80) +
+				`","payload":"` + strings.Repeat("p", 300) + `","message":"` + strings.Repeat("m", 120) + `"}`,
+			maxSize:       200,
+			unchanged:     []string{"severity", "time"},
+			wantTruncated: []string{"user", "payload", "message"},
+			wantPrefix:    map[string]string{"message": strings.Repeat("m", 40)},
+		},
+		{
+			name:          "small fields before message",
+			entry:         `{"severity":"INFO","user":"ana","message":"` + strings.Repeat("m", 300) + `"}`,
+			maxSize:       200,
+			unchanged:     []string{"severity", "user"},
+			wantTruncated: []string{"message"},
+			wantPrefix:    map[string]string{"message": strings.Repeat("m", 100)},
+		},
+		{
+			name: "protected fields",
+			entry: `{"severity":"INFO","time":"2026-10-18T12:00:00Z","logging.googleapis.com/trace":"` +
+				strings.Repeat("t", 150) + `","logging.googleapis.com/sourceLocation":{"file":"` +
+				strings.Repeat("f", 150) + `"},"message":"hello"}`,
+			maxSize: 200,
+			unchanged: []string{"severity", "time", "logging.googleapis.com/trace",
+				"logging.googleapis.com/sourceLocation"},
+			wantTruncated: []string{"message"},
+			oversized:     true,
+		},
+		{
+			name:          "multibyte",
+			entry:         `{"severity":"INFO","message":"` + strings.Repeat("é", 200) + `"}`,
+			maxSize:       100,
+			unchanged:     []string{"severity"},
+			wantTruncated: []string{"message"},
+			wantPrefix:    map[string]string{"message": strings.Repeat("é", 20)},
+		},
+		{
+			name:          "not a string",
+			entry:         `{"severity":"INFO","items":[` + strings.Repeat(`1,`, 150) + `1],"message":"hello"}`,
+			maxSize:       100,
+			unchanged:     []string{"severity", "message"},
+			wantTruncated: []string{"items"},
+			wantPrefix:    map[string]string{"items": "[1,1,1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := tt.entry + "\n"
+
+			var b bytes.Buffer
+			w := NewSizeLimitWriter(&b, WithMaxEntrySize(tt.maxSize), WithMeterProvider(noop.NewMeterProvider()))
+			if _, err := w.Write([]byte(entry)); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			out := b.Bytes()
+
+			if fits := len(out) <= tt.maxSize; fits == tt.oversized {
+				t.Errorf("wrote %d bytes, max %d: %s", len(out), tt.maxSize, out)
+			}
+			if !utf8.Valid(out) {
+				t.Errorf("wrote invalid UTF-8: %q", out)
+			}
+
+			var before, after map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.entry), &before); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(out, &after); err != nil {
+				t.Fatalf("wrote invalid JSON %s: %v", out, err)
+			}
+
+			for _, name := range tt.unchanged {
+				if !bytes.Equal(after[name], before[name]) {
+					t.Errorf("%s = %s, want %s", name, after[name], before[name])
+				}
+			}
+
+			var truncated []string
+			if raw, ok := after[TruncatedFieldName]; ok {
+				if err := json.Unmarshal(raw, &truncated); err != nil {
+					t.Fatalf("%s = %s: %v", TruncatedFieldName, raw, err)
+				}
+			}
+			slices.Sort(truncated)
+			want := slices.Sorted(slices.Values(tt.wantTruncated))
+			if !slices.Equal(truncated, want) {
+				t.Errorf("%s = %v, want %v", TruncatedFieldName, truncated, want)
+			}
+
+			for name, prefix := range tt.wantPrefix {
+				var s string
+				if err := json.Unmarshal(after[name], &s); err != nil {
+					t.Fatalf("%s = %s: %v", name, after[name], err)
+				}
+				if !strings.HasPrefix(s, prefix) || !strings.HasSuffix(s, truncationSuffix) {
+					t.Errorf("%s = %q, want a prefix of %q followed by %q", name, s, prefix, truncationSuffix)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncateValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		size  int
+		want  string
+	}{
+		{
+			name:  "ascii",
+			value: `"hello world"`,
+			size:  10,
+			want:  `"hello…"`,
+		},
+		{
+			name:  "multibyte",
+			value: `"ééééé"`,
+			size:  8,
+			want:  `"é…"`,
+		},
+		{
+			name:  "escaped",
+			value: `"a\"b\"c\"d"`,
+			size:  10,
+			want:  `"a\"b…"`,
+		},
+		{
+			name:  "not a string",
+			value: `{"a":1}`,
+			size:  10,
+			want:  `"{\"a…"`,
+		},
+		{
+			name:  "too small",
+			value: `"hello world"`,
+			size:  2,
+			want:  `"…"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(truncateValue([]byte(tt.value), tt.size)); got != tt.want {
+				t.Errorf("truncateValue(%s, %d) = %s, want %s", tt.value, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizeLimitWriterNotJSON(t *testing.T) {
+	entry := strings.Repeat("x", 300) + "\n"
+
+	var b bytes.Buffer
+	w := NewSizeLimitWriter(&b, WithMaxEntrySize(100), WithMeterProvider(noop.NewMeterProvider()))
+	if _, err := w.Write([]byte(entry)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if b.String() != entry {
+		t.Errorf("wrote %q, want %q", b.String(), entry)
+	}
+}